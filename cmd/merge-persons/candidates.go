@@ -0,0 +1,372 @@
+// cmd/merge-persons/candidates.go
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/lib/pq"
+)
+
+// maxNameGroup caps how many persons sharing one name key are paired up.
+// Bigger groups ("John Smith") are mostly different people and would only
+// flood the list.
+const maxNameGroup = 25
+
+type personInfo struct {
+	id        int64
+	name      string
+	imdbID    sql.NullString
+	birthYear sql.NullInt64
+	credits   int64 // title_cast + title_award rows
+}
+
+func (p *personInfo) String() string {
+	s := fmt.Sprintf("%d %q", p.id, p.name)
+	if p.imdbID.Valid {
+		s += " " + p.imdbID.String
+	}
+	if p.birthYear.Valid {
+		s += fmt.Sprintf(" b.%d", p.birthYear.Int64)
+	}
+	return s + fmt.Sprintf(" (%d credits)", p.credits)
+}
+
+// candidate is a pair of persons that look like the same person.
+type candidate struct {
+	winner, loser *personInfo
+	score         int
+	shared        int
+	reasons       []string
+}
+
+func (c *candidate) reason() string {
+	return strings.Join(c.reasons, ", ")
+}
+
+type pairKey struct{ a, b int64 }
+
+func newPairKey(a, b int64) pairKey {
+	if a > b {
+		a, b = b, a
+	}
+	return pairKey{a, b}
+}
+
+// findCandidates scores person pairs on:
+//
+//   - same normalized name (case, accents, punctuation, "Last, First"): +2
+//   - same name tokens in another order: +2
+//   - same first initial and last name: +1, kept only with shared titles
+//   - each shared title in title_cast: +1, at most +3
+//   - imdb-ids proposed the same nconst for both (imdb_id_review): +3
+//
+// Pairs with two different imdb_ids or birth years are never candidates.
+func findCandidates(ctx context.Context, db *sql.DB, minScore int) ([]*candidate, error) {
+	persons, err := loadPersons(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("find: %d persons", len(persons))
+
+	pairs := make(map[pairKey]*candidate)
+	add := func(a, b int64, points int, reason string) {
+		k := newPairKey(a, b)
+		c := pairs[k]
+		if c == nil {
+			c = &candidate{winner: persons[k.a], loser: persons[k.b]}
+			pairs[k] = c
+		}
+		c.score += points
+		c.reasons = append(c.reasons, reason)
+	}
+
+	byName := make(map[string][]int64)
+	byTokens := make(map[string][]int64)
+	byInitial := make(map[string][]int64)
+	for _, p := range persons {
+		name := normalizeName(p.name)
+		if name == "" {
+			continue
+		}
+		byName[name] = append(byName[name], p.id)
+		byTokens[sortedTokens(name)] = append(byTokens[sortedTokens(name)], p.id)
+		if k := initialKey(name); k != "" {
+			byInitial[k] = append(byInitial[k], p.id)
+		}
+	}
+
+	sameName := make(map[pairKey]bool)
+	forEachPair(byName, func(a, b int64) {
+		sameName[newPairKey(a, b)] = true
+		add(a, b, 2, "same normalized name")
+	})
+	forEachPair(byTokens, func(a, b int64) {
+		if !sameName[newPairKey(a, b)] {
+			add(a, b, 2, "same name, reordered")
+		}
+	})
+	initialOnly := make(map[pairKey]bool)
+	forEachPair(byInitial, func(a, b int64) {
+		k := newPairKey(a, b)
+		if pairs[k] == nil {
+			initialOnly[k] = true
+			add(a, b, 1, "same initial and last name")
+		}
+	})
+
+	if err := scoreSharedTitles(ctx, db, pairs); err != nil {
+		return nil, err
+	}
+	for k := range initialOnly {
+		if pairs[k].shared == 0 {
+			delete(pairs, k)
+		}
+	}
+
+	if err := scoreImdbReview(ctx, db, persons, add); err != nil {
+		return nil, err
+	}
+
+	var out []*candidate
+	for _, c := range pairs {
+		w, l := c.winner, c.loser
+		if w.imdbID.Valid && l.imdbID.Valid && w.imdbID.String != l.imdbID.String {
+			continue
+		}
+		if w.birthYear.Valid && l.birthYear.Valid && w.birthYear.Int64 != l.birthYear.Int64 {
+			continue
+		}
+		if c.score < minScore {
+			continue
+		}
+		if preferLoser(w, l) {
+			c.winner, c.loser = l, w
+		}
+		out = append(out, c)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].score != out[j].score {
+			return out[i].score > out[j].score
+		}
+		return out[i].winner.id < out[j].winner.id
+	})
+	log.Printf("find: %d candidate pairs with score >= %d", len(out), minScore)
+	return out, nil
+}
+
+// preferLoser reports whether l should survive instead of w: the one with an
+// imdb_id wins, then the one with more credits, then the lower id.
+func preferLoser(w, l *personInfo) bool {
+	if w.imdbID.Valid != l.imdbID.Valid {
+		return l.imdbID.Valid
+	}
+	if w.credits != l.credits {
+		return l.credits > w.credits
+	}
+	return l.id < w.id
+}
+
+func loadPersons(ctx context.Context, db *sql.DB) (map[int64]*personInfo, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT p.id, p.name, p.imdb_id, p.birth_year,
+		       COALESCE(c.n, 0) + COALESCE(a.n, 0)
+		FROM person p
+		LEFT JOIN (SELECT person_id, COUNT(*) AS n FROM title_cast GROUP BY person_id) c ON c.person_id = p.id
+		LEFT JOIN (SELECT person_id, COUNT(*) AS n FROM title_award GROUP BY person_id) a ON a.person_id = p.id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("select persons: %w", err)
+	}
+	defer rows.Close()
+
+	out := make(map[int64]*personInfo)
+	for rows.Next() {
+		p := &personInfo{}
+		if err := rows.Scan(&p.id, &p.name, &p.imdbID, &p.birthYear, &p.credits); err != nil {
+			return nil, fmt.Errorf("scan person: %w", err)
+		}
+		out[p.id] = p
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate persons: %w", err)
+	}
+	return out, nil
+}
+
+// forEachPair calls fn for every pair of IDs in each group, skipping groups
+// larger than maxNameGroup.
+func forEachPair(groups map[string][]int64, fn func(a, b int64)) {
+	var skipped int
+	for _, ids := range groups {
+		if len(ids) > maxNameGroup {
+			skipped++
+			continue
+		}
+		for i := 0; i < len(ids); i++ {
+			for j := i + 1; j < len(ids); j++ {
+				fn(ids[i], ids[j])
+			}
+		}
+	}
+	if skipped > 0 {
+		log.Printf("find: skipped %d name groups with more than %d persons", skipped, maxNameGroup)
+	}
+}
+
+// scoreSharedTitles counts the titles both persons of each pair are cast in.
+func scoreSharedTitles(ctx context.Context, db *sql.DB, pairs map[pairKey]*candidate) error {
+	involved := make(map[int64]bool)
+	for k := range pairs {
+		involved[k.a], involved[k.b] = true, true
+	}
+	ids := make([]int64, 0, len(involved))
+	for id := range involved {
+		ids = append(ids, id)
+	}
+
+	titles, err := loadPersonTitles(ctx, db, ids)
+	if err != nil {
+		return err
+	}
+	for k, c := range pairs {
+		for t := range titles[k.a] {
+			if titles[k.b][t] {
+				c.shared++
+			}
+		}
+		if c.shared > 0 {
+			c.score += min(c.shared, 3)
+			c.reasons = append(c.reasons, fmt.Sprintf("%d shared titles", c.shared))
+		}
+	}
+	return nil
+}
+
+func loadPersonTitles(ctx context.Context, db *sql.DB, personIDs []int64) (map[int64]map[int64]bool, error) {
+	out := make(map[int64]map[int64]bool)
+	if len(personIDs) == 0 {
+		return out, nil
+	}
+	rows, err := db.QueryContext(ctx, `
+		SELECT DISTINCT person_id, title_id FROM title_cast WHERE person_id = ANY($1)
+	`, pq.Array(personIDs))
+	if err != nil {
+		return nil, fmt.Errorf("select person titles: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var personID, titleID int64
+		if err := rows.Scan(&personID, &titleID); err != nil {
+			return nil, fmt.Errorf("scan person title: %w", err)
+		}
+		if out[personID] == nil {
+			out[personID] = make(map[int64]bool)
+		}
+		out[personID][titleID] = true
+	}
+	return out, rows.Err()
+}
+
+// scoreImdbReview pairs persons the imdb-ids phase held back because the
+// nconst it found was already on another person.
+func scoreImdbReview(ctx context.Context, db *sql.DB, persons map[int64]*personInfo, add func(a, b int64, points int, reason string)) error {
+	rows, err := db.QueryContext(ctx, `
+		SELECT DISTINCT r.entity_id, p.id, p.imdb_id
+		FROM imdb_id_review r
+		JOIN person p ON p.imdb_id = r.candidates[1]
+		WHERE r.entity = 'person' AND cardinality(r.candidates) = 1 AND p.id <> r.entity_id
+	`)
+	if err != nil {
+		return fmt.Errorf("select imdb_id_review matches: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var a, b int64
+		var nconst string
+		if err := rows.Scan(&a, &b, &nconst); err != nil {
+			return fmt.Errorf("scan imdb_id_review match: %w", err)
+		}
+		if persons[a] != nil && persons[b] != nil {
+			add(a, b, 3, "both match imdb_id "+nconst)
+		}
+	}
+	return rows.Err()
+}
+
+var accentFolder = strings.NewReplacer(
+	"á", "a", "à", "a", "â", "a", "ä", "a", "ã", "a", "å", "a", "ā", "a",
+	"é", "e", "è", "e", "ê", "e", "ë", "e", "ē", "e",
+	"í", "i", "ì", "i", "î", "i", "ï", "i",
+	"ó", "o", "ò", "o", "ô", "o", "ö", "o", "õ", "o", "ø", "o",
+	"ú", "u", "ù", "u", "û", "u", "ü", "u",
+	"ñ", "n", "ç", "c", "ß", "ss", "ý", "y", "ÿ", "y",
+	"ł", "l", "ś", "s", "š", "s", "ž", "z", "ź", "z", "ż", "z", "č", "c", "ć", "c", "ř", "r",
+)
+
+// normalizeName lowercases name, folds common accents, turns "Last, First"
+// into "first last" and keeps only letters and digits, single-space separated.
+func normalizeName(name string) string {
+	if last, first, ok := strings.Cut(name, ","); ok && !strings.Contains(first, ",") {
+		name = first + " " + last
+	}
+	name = accentFolder.Replace(strings.ToLower(name))
+	return strings.Join(strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+func sortedTokens(name string) string {
+	tokens := strings.Fields(name)
+	sort.Strings(tokens)
+	return strings.Join(tokens, " ")
+}
+
+// initialKey is "<first initial> <last name>" for names of two or more tokens.
+func initialKey(name string) string {
+	tokens := strings.Fields(name)
+	if len(tokens) < 2 {
+		return ""
+	}
+	first := []rune(tokens[0])
+	return string(first[0]) + " " + tokens[len(tokens)-1]
+}
+
+// runFind prints the candidates and, with -out, writes them as a -merge file.
+func runFind(ctx context.Context, db *sql.DB) error {
+	cands, err := findCandidates(ctx, db, *minScore)
+	if err != nil {
+		return err
+	}
+	for _, c := range cands {
+		log.Printf("score=%-2d keep %s <- merge %s: %s", c.score, c.winner, c.loser, c.reason())
+	}
+	if *out == "" {
+		return nil
+	}
+
+	f, err := os.Create(*out)
+	if err != nil {
+		return fmt.Errorf("create %s: %w", *out, err)
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	fmt.Fprintf(w, "# merge-persons candidates (min-score %d), one \"winner_id loser_id\" per line.\n", *minScore)
+	fmt.Fprintf(w, "# Delete the pairs that are not the same person, swap the IDs to keep the\n")
+	fmt.Fprintf(w, "# other one, then run: merge-persons -merge %s\n", *out)
+	for _, c := range cands {
+		fmt.Fprintf(w, "%d %d  # score=%d %q <- %q: %s\n", c.winner.id, c.loser.id, c.score, c.winner.name, c.loser.name, c.reason())
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("write %s: %w", *out, err)
+	}
+	log.Printf("find: wrote %d pairs to %s", len(cands), *out)
+	return nil
+}
@@ -0,0 +1,94 @@
+// cmd/merge-persons/main.go
+//
+// merge-persons finds and merges duplicate person rows in the NEW DB. The old
+// CastTable holds the same people under several CastIDs (spelling variants,
+// "Last, First"), and core-persons copies them 1:1.
+//
+//	merge-persons -new DSN -find [-out merges.txt]     list candidate pairs
+//	merge-persons -new DSN -interactive                 walk the candidates, ask per pair
+//	merge-persons -new DSN -merge merges.txt [-dry-run] merge "winner loser" lines
+//	merge-persons -new DSN -undo 42                     revert person_merge_log id 42
+//
+// A merge repoints the loser's title_cast / title_award rows to the winner
+// (rows the winner already has are dropped), points the loser's id_map rows
+// at the winner, fills the winner's empty columns from the loser and deletes
+// the loser. Everything it changed goes to person_merge_log, which -undo
+// replays backwards.
+//
+// The 'merge' id_map rows survive re-runs of migrate-old-db: core-persons and
+// sync do not re-create merged-away persons, and the cast and award loads
+// resolve their CastIDs to the person they were merged into.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	_ "github.com/lib/pq"
+)
+
+var (
+	newDSN = flag.String("new", "", "Postgres DSN for NEW database (movies3db)")
+	dryRun = flag.Bool("dry-run", false, "with -merge / -interactive / -undo: do everything in a transaction and roll it back")
+
+	find        = flag.Bool("find", false, "list candidate duplicate pairs")
+	out         = flag.String("out", "", "with -find: also write the pairs as a -merge file")
+	minScore    = flag.Int("min-score", 3, "with -find / -interactive: only pairs scoring at least this much")
+	interactive = flag.Bool("interactive", false, "walk the candidate pairs and ask before merging each")
+	mergeFile   = flag.String("merge", "", "merge the \"winner_id loser_id\" pairs listed in this file")
+	undo        = flag.Int64("undo", 0, "undo the merge with this person_merge_log id")
+)
+
+func main() {
+	log.SetOutput(os.Stdout)
+	flag.Parse()
+
+	modes := 0
+	for _, set := range []bool{*find, *interactive, *mergeFile != "", *undo != 0} {
+		if set {
+			modes++
+		}
+	}
+	if modes != 1 {
+		log.Printf("ERROR: exactly one of -find, -interactive, -merge or -undo is required")
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *newDSN == "" {
+		log.Printf("ERROR: -new DSN is required")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	log.Printf("Connecting to NEW DB: %s", *newDSN)
+	db, err := sql.Open("postgres", *newDSN)
+	if err != nil {
+		log.Fatalf("open new DB: %v", err)
+	}
+	defer db.Close()
+	if err := db.PingContext(ctx); err != nil {
+		log.Fatalf("ping new DB: %v", err)
+	}
+
+	switch {
+	case *find:
+		err = runFind(ctx, db)
+	case *interactive:
+		err = runInteractive(ctx, db, os.Stdin)
+	case *mergeFile != "":
+		err = runMergeFile(ctx, db, *mergeFile)
+	default:
+		err = runUndo(ctx, db, *undo)
+	}
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+}
@@ -0,0 +1,437 @@
+// cmd/merge-persons/merge.go
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// errDryRun rolls back a merge or undo under -dry-run.
+var errDryRun = errors.New("dry-run")
+
+// mergeResult is what one merge moved, as logged to person_merge_log.
+type mergeResult struct {
+	logID                    int64
+	castMoved, castDropped   int
+	awardMoved, awardDropped int
+	idMapRows                int
+}
+
+func (r mergeResult) String() string {
+	return fmt.Sprintf("title_cast %d moved / %d dropped, title_award %d moved / %d dropped, %d id_map rows",
+		r.castMoved, r.castDropped, r.awardMoved, r.awardDropped, r.idMapRows)
+}
+
+// mergePersons merges loser into winner in one transaction and records the
+// undo data in person_merge_log.
+func mergePersons(ctx context.Context, db *sql.DB, winner, loser int64, reason string) (mergeResult, error) {
+	var res mergeResult
+	if winner == loser {
+		return res, fmt.Errorf("cannot merge person %d into itself", winner)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return res, fmt.Errorf("begin tx (merge %d <- %d): %w", winner, loser, err)
+	}
+	defer tx.Rollback()
+
+	var winnerRow, loserRow []byte
+	for _, p := range []struct {
+		id  int64
+		dst *[]byte
+	}{{winner, &winnerRow}, {loser, &loserRow}} {
+		err := tx.QueryRowContext(ctx, `SELECT to_jsonb(p) FROM person p WHERE id = $1 FOR UPDATE`, p.id).Scan(p.dst)
+		if errors.Is(err, sql.ErrNoRows) {
+			return res, fmt.Errorf("person %d not found (already merged?)", p.id)
+		}
+		if err != nil {
+			return res, fmt.Errorf("lock person %d: %w", p.id, err)
+		}
+	}
+
+	var conflict bool
+	if err := tx.QueryRowContext(ctx, `
+		SELECT w.imdb_id IS NOT NULL AND l.imdb_id IS NOT NULL AND w.imdb_id <> l.imdb_id
+		FROM person w, person l
+		WHERE w.id = $1 AND l.id = $2
+	`, winner, loser).Scan(&conflict); err != nil {
+		return res, fmt.Errorf("compare imdb_id: %w", err)
+	}
+	if conflict {
+		return res, fmt.Errorf("persons %d and %d have different imdb_ids", winner, loser)
+	}
+
+	// Each step returns the rows it touched as a JSON array for the undo log.
+	steps := []struct {
+		name  string
+		query string
+		count *int
+		dst   *[]byte
+	}{
+		{"title_cast dropped", `
+			WITH d AS (
+				DELETE FROM title_cast l
+				USING title_cast w
+				WHERE l.person_id = $2 AND w.person_id = $1
+				  AND w.title_id = l.title_id AND w.role_type_id = l.role_type_id
+				RETURNING l.*
+			)
+			SELECT COUNT(*), COALESCE(jsonb_agg(to_jsonb(d)), '[]') FROM d`, &res.castDropped, new([]byte)},
+		{"title_cast moved", `
+			WITH m AS (
+				UPDATE title_cast SET person_id = $1 WHERE person_id = $2
+				RETURNING title_id, role_type_id
+			)
+			SELECT COUNT(*), COALESCE(jsonb_agg(to_jsonb(m)), '[]') FROM m`, &res.castMoved, new([]byte)},
+		{"title_award dropped", `
+			WITH d AS (
+				DELETE FROM title_award l
+				USING title_award w
+				WHERE l.person_id = $2 AND w.person_id = $1
+				  AND w.title_id = l.title_id AND w.event_id = l.event_id
+				  AND w.nomination_type_id = l.nomination_type_id
+				  AND w.award_year IS NOT DISTINCT FROM l.award_year
+				  AND w.category IS NOT DISTINCT FROM l.category
+				  AND w.description IS NOT DISTINCT FROM l.description
+				RETURNING l.*
+			)
+			SELECT COUNT(*), COALESCE(jsonb_agg(to_jsonb(d)), '[]') FROM d`, &res.awardDropped, new([]byte)},
+		{"title_award moved", `
+			WITH m AS (
+				UPDATE title_award SET person_id = $1 WHERE person_id = $2
+				RETURNING id
+			)
+			SELECT COUNT(*), COALESCE(jsonb_agg(to_jsonb(m)), '[]') FROM m`, &res.awardMoved, new([]byte)},
+		{"id_map", `
+			WITH prev AS (
+				SELECT old_id, match_method FROM id_map
+				WHERE entity = 'person' AND new_id = $2
+				FOR UPDATE
+			), u AS (
+				UPDATE id_map i
+				SET new_id = $1, match_method = 'merge', updated_at = now()
+				FROM prev
+				WHERE i.entity = 'person' AND i.old_id = prev.old_id
+				RETURNING prev.old_id, prev.match_method
+			)
+			SELECT COUNT(*), COALESCE(jsonb_agg(to_jsonb(u)), '[]') FROM u`, &res.idMapRows, new([]byte)},
+	}
+	for _, s := range steps {
+		if err := tx.QueryRowContext(ctx, s.query, winner, loser).Scan(s.count, s.dst); err != nil {
+			return res, fmt.Errorf("merge %d <- %d: %s: %w", winner, loser, s.name, err)
+		}
+	}
+
+	// The loser goes first: imdb_id is UNIQUE and may move to the winner.
+	if _, err := tx.ExecContext(ctx, `DELETE FROM person WHERE id = $1`, loser); err != nil {
+		return res, fmt.Errorf("delete person %d: %w", loser, err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE person w
+		SET imdb_id            = COALESCE(w.imdb_id, l.imdb_id),
+		    birth_year         = COALESCE(w.birth_year, l.birth_year),
+		    death_year         = COALESCE(w.death_year, l.death_year),
+		    primary_profession = COALESCE(w.primary_profession, l.primary_profession),
+		    image_url          = COALESCE(w.image_url, l.image_url),
+		    bio                = COALESCE(w.bio, l.bio),
+		    updated_at         = now()
+		FROM jsonb_populate_record(NULL::person, $2) l
+		WHERE w.id = $1
+	`, winner, loserRow); err != nil {
+		return res, fmt.Errorf("fill person %d from %d: %w", winner, loser, err)
+	}
+
+	if err := tx.QueryRowContext(ctx, `
+		INSERT INTO person_merge_log
+			(winner_id, loser_id, reason, winner_before, loser,
+			 cast_dropped, cast_moved, award_dropped, award_moved, id_map)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id
+	`, winner, loser, reason, winnerRow, loserRow,
+		*steps[0].dst, *steps[1].dst, *steps[2].dst, *steps[3].dst, *steps[4].dst).Scan(&res.logID); err != nil {
+		return res, fmt.Errorf("insert person_merge_log: %w", err)
+	}
+
+	if *dryRun {
+		return res, errDryRun
+	}
+	if err := tx.Commit(); err != nil {
+		return res, fmt.Errorf("commit merge %d <- %d: %w", winner, loser, err)
+	}
+	return res, nil
+}
+
+// undoMerge reverts one person_merge_log entry: the loser is re-created, its
+// moved rows are pointed back at it and its dropped rows re-inserted.
+func undoMerge(ctx context.Context, db *sql.DB, logID int64) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx (undo %d): %w", logID, err)
+	}
+	defer tx.Rollback()
+
+	var winner, loser int64
+	var undone sql.NullTime
+	err = tx.QueryRowContext(ctx, `
+		SELECT winner_id, loser_id, undone_at FROM person_merge_log WHERE id = $1 FOR UPDATE
+	`, logID).Scan(&winner, &loser, &undone)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("person_merge_log %d not found", logID)
+	}
+	if err != nil {
+		return fmt.Errorf("read person_merge_log %d: %w", logID, err)
+	}
+	if undone.Valid {
+		return fmt.Errorf("person_merge_log %d was already undone at %s", logID, undone.Time)
+	}
+
+	// Later merges built on this one must be undone first.
+	var later sql.NullInt64
+	if err := tx.QueryRowContext(ctx, `
+		SELECT MIN(id) FROM person_merge_log
+		WHERE id > $1 AND undone_at IS NULL AND (loser_id = $2 OR winner_id = $3)
+	`, logID, winner, loser).Scan(&later); err != nil {
+		return fmt.Errorf("check later merges: %w", err)
+	}
+	if later.Valid {
+		return fmt.Errorf("person_merge_log %d depends on this merge; undo it first", later.Int64)
+	}
+
+	steps := []struct {
+		name  string
+		query string
+	}{
+		{"restore winner", `
+			UPDATE person w
+			SET imdb_id            = b.imdb_id,
+			    birth_year         = b.birth_year,
+			    death_year         = b.death_year,
+			    primary_profession = b.primary_profession,
+			    image_url          = b.image_url,
+			    bio                = b.bio,
+			    updated_at         = now()
+			FROM person_merge_log g, jsonb_populate_record(NULL::person, g.winner_before) b
+			WHERE g.id = $1 AND w.id = g.winner_id`},
+		{"re-create loser", `
+			INSERT INTO person
+			SELECT l.* FROM person_merge_log g, jsonb_populate_record(NULL::person, g.loser) l
+			WHERE g.id = $1`},
+		{"title_cast moved back", `
+			UPDATE title_cast c
+			SET person_id = g.loser_id
+			FROM person_merge_log g, jsonb_to_recordset(g.cast_moved) AS m(title_id INTEGER, role_type_id SMALLINT)
+			WHERE g.id = $1 AND c.person_id = g.winner_id
+			  AND c.title_id = m.title_id AND c.role_type_id = m.role_type_id`},
+		{"title_cast re-inserted", `
+			INSERT INTO title_cast
+			SELECT d.* FROM person_merge_log g, jsonb_populate_recordset(NULL::title_cast, g.cast_dropped) d
+			WHERE g.id = $1
+			ON CONFLICT DO NOTHING`},
+		{"title_award moved back", `
+			UPDATE title_award a
+			SET person_id = g.loser_id
+			FROM person_merge_log g, jsonb_to_recordset(g.award_moved) AS m(id BIGINT)
+			WHERE g.id = $1 AND a.person_id = g.winner_id AND a.id = m.id`},
+		{"title_award re-inserted", `
+			INSERT INTO title_award
+			SELECT d.* FROM person_merge_log g, jsonb_populate_recordset(NULL::title_award, g.award_dropped) d
+			WHERE g.id = $1
+			ON CONFLICT DO NOTHING`},
+		{"id_map restored", `
+			UPDATE id_map i
+			SET new_id = g.loser_id, match_method = p.match_method, updated_at = now()
+			FROM person_merge_log g, jsonb_to_recordset(g.id_map) AS p(old_id BIGINT, match_method TEXT)
+			WHERE g.id = $1 AND i.entity = 'person' AND i.old_id = p.old_id AND i.new_id = g.winner_id`},
+		{"mark undone", `UPDATE person_merge_log SET undone_at = now() WHERE id = $1`},
+	}
+	for _, s := range steps {
+		r, err := tx.ExecContext(ctx, s.query, logID)
+		if err != nil {
+			return fmt.Errorf("undo %d: %s: %w", logID, s.name, err)
+		}
+		n, _ := r.RowsAffected()
+		log.Printf("undo %d: %s: %d rows", logID, s.name, n)
+	}
+
+	if *dryRun {
+		return errDryRun
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit undo %d: %w", logID, err)
+	}
+	return nil
+}
+
+// runMergeFile merges every "winner_id loser_id" line of path. A failing pair
+// is logged and skipped; the run fails at the end if any did.
+func runMergeFile(ctx context.Context, db *sql.DB, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	type pair struct {
+		winner, loser int64
+		line          int
+		note          string
+	}
+	var pairs []pair
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		line, note, _ := strings.Cut(sc.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return fmt.Errorf("%s:%d: expected \"winner_id loser_id\", got %q", path, n, line)
+		}
+		winner, err1 := strconv.ParseInt(fields[0], 10, 64)
+		loser, err2 := strconv.ParseInt(fields[1], 10, 64)
+		if err1 != nil || err2 != nil {
+			return fmt.Errorf("%s:%d: person ids must be numbers, got %q", path, n, line)
+		}
+		pairs = append(pairs, pair{winner, loser, n, strings.TrimSpace(note)})
+	}
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("read %s: %w", path, err)
+	}
+	log.Printf("merge: %d pairs in %s dryRun=%v", len(pairs), path, *dryRun)
+
+	var merged, failed int
+	for _, p := range pairs {
+		reason := fmt.Sprintf("%s:%d", path, p.line)
+		if p.note != "" {
+			reason += " " + p.note
+		}
+		res, err := mergePersons(ctx, db, p.winner, p.loser, reason)
+		if err := reportMerge(p.winner, p.loser, res, err); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			log.Printf("ERROR: %s:%d: %v", path, p.line, err)
+			failed++
+			continue
+		}
+		merged++
+	}
+
+	log.Printf("merge: %d merged, %d failed", merged, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d merges failed", failed, len(pairs))
+	}
+	return nil
+}
+
+// runInteractive walks the candidates and asks before merging each pair.
+func runInteractive(ctx context.Context, db *sql.DB, in io.Reader) error {
+	cands, err := findCandidates(ctx, db, *minScore)
+	if err != nil {
+		return err
+	}
+
+	gone := make(map[int64]bool)
+	answers := bufio.NewScanner(in)
+	for i, c := range cands {
+		if gone[c.winner.id] || gone[c.loser.id] {
+			continue
+		}
+		shared, err := sharedTitleNames(ctx, db, c.winner.id, c.loser.id, 5)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("\n[%d/%d] score=%d: %s\n", i+1, len(cands), c.score, c.reason())
+		fmt.Printf("  keep  %s\n", c.winner)
+		fmt.Printf("  merge %s\n", c.loser)
+		if len(shared) > 0 {
+			fmt.Printf("  shared: %s\n", strings.Join(shared, "; "))
+		}
+		fmt.Printf("merge %d into %d? [y]es / [s]wap and merge / [n]o / [q]uit: ", c.loser.id, c.winner.id)
+		if !answers.Scan() {
+			return answers.Err()
+		}
+
+		winner, loser := c.winner.id, c.loser.id
+		switch strings.ToLower(strings.TrimSpace(answers.Text())) {
+		case "y", "yes":
+		case "s", "swap":
+			winner, loser = loser, winner
+		case "q", "quit":
+			return nil
+		default:
+			continue
+		}
+
+		res, err := mergePersons(ctx, db, winner, loser, "interactive: "+c.reason())
+		if err := reportMerge(winner, loser, res, err); err != nil {
+			log.Printf("ERROR: %v", err)
+			continue
+		}
+		if !*dryRun {
+			gone[loser] = true
+		}
+	}
+	return nil
+}
+
+// reportMerge logs the outcome of one mergePersons call and returns its error,
+// treating a -dry-run rollback as success.
+func reportMerge(winner, loser int64, res mergeResult, err error) error {
+	switch {
+	case errors.Is(err, errDryRun):
+		log.Printf("merge [DRY-RUN]: %d <- %d would move %s", winner, loser, res)
+	case err != nil:
+		return err
+	default:
+		log.Printf("merge: %d <- %d: %s (undo with -undo %d)", winner, loser, res, res.logID)
+	}
+	return nil
+}
+
+func runUndo(ctx context.Context, db *sql.DB, logID int64) error {
+	err := undoMerge(ctx, db, logID)
+	switch {
+	case errors.Is(err, errDryRun):
+		log.Printf("undo [DRY-RUN]: person_merge_log %d rolled back", logID)
+	case err != nil:
+		return err
+	default:
+		log.Printf("undo: person_merge_log %d reverted", logID)
+	}
+	return nil
+}
+
+func sharedTitleNames(ctx context.Context, db *sql.DB, a, b int64, limit int) ([]string, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT DISTINCT t.primary_title || COALESCE(' (' || t.start_year || ')', '')
+		FROM title_cast ca
+		JOIN title_cast cb ON cb.title_id = ca.title_id AND cb.person_id = $2
+		JOIN title t ON t.id = ca.title_id
+		WHERE ca.person_id = $1
+		LIMIT $3
+	`, a, b, limit)
+	if err != nil {
+		return nil, fmt.Errorf("select shared titles: %w", err)
+	}
+	defer rows.Close()
+	var out []string
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			return nil, fmt.Errorf("scan shared title: %w", err)
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
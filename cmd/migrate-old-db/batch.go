@@ -0,0 +1,257 @@
+// cmd/migrate-old-db/batch.go
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/lib/pq"
+)
+
+// batchWriter runs a loader's statements once per row, -batch-size rows per
+// transaction. A batch that fails is rolled back and replayed row by row, each
+// row in its own transaction; rows that still fail with a data or constraint
+// error (bad date, over-long value, FK, ...) are written to migration_reject
+// and the load goes on. Any other error (lost connection, missing column,
+// cancelled ctx) still aborts the phase.
+type batchWriter struct {
+	db      *sql.DB
+	phase   string
+	target  string
+	queries []string // run in order with the same args
+	columns []string // names of the args, for migration_reject.source_row
+	size    int
+
+	// onCommit, if set, runs after each committed batch with the key of its
+	// last row and the rows done so far, e.g. to save a checkpoint.
+	onCommit func(ctx context.Context, lastKey, done int64) error
+
+	pending  []batchRow
+	lastKey  int64 // key of the last row of the last committed batch
+	written  int64 // rows committed
+	affected int64 // RowsAffected of the last query, summed over committed rows
+	rejected int64
+}
+
+// batchRow is one pending row. key identifies it in migration_reject (an old
+// ID, usually TitleID / CastID); 0 when there is none.
+type batchRow struct {
+	key  int64
+	args []interface{}
+}
+
+// newBatchWriter returns a writer for target. columns name the args passed to
+// add. Rejects of an earlier run of the same phase and target are cleared,
+// unless -resume continues that run.
+func newBatchWriter(ctx context.Context, db *sql.DB, phase, target string, queries []string, columns ...string) (*batchWriter, error) {
+	if err := resetRejects(ctx, db, phase, target); err != nil {
+		return nil, err
+	}
+	return openBatchWriter(db, phase, target, queries, columns...), nil
+}
+
+// openBatchWriter is newBatchWriter without the reset, for workers sharing
+// one phase and target.
+func openBatchWriter(db *sql.DB, phase, target string, queries []string, columns ...string) *batchWriter {
+	return &batchWriter{
+		db:      db,
+		phase:   phase,
+		target:  target,
+		queries: queries,
+		columns: columns,
+		size:    *batchSize,
+	}
+}
+
+// resetRejects deletes the migration_reject rows of phase and target, unless
+// -resume continues the run that wrote them.
+func resetRejects(ctx context.Context, db *sql.DB, phase, target string) error {
+	if *resume {
+		return nil
+	}
+	if _, err := db.ExecContext(ctx, `
+		DELETE FROM migration_reject WHERE phase = $1 AND target = $2
+	`, phase, target); err != nil {
+		return fmt.Errorf("clear migration_reject %s/%s: %w", phase, target, err)
+	}
+	return nil
+}
+
+// add queues one row and commits the batch once it is full.
+func (w *batchWriter) add(ctx context.Context, key int64, args ...interface{}) error {
+	w.pending = append(w.pending, batchRow{key: key, args: args})
+	if len(w.pending) >= w.size {
+		return w.flush(ctx)
+	}
+	return nil
+}
+
+// flush commits the pending rows, falling back to row-by-row on failure.
+func (w *batchWriter) flush(ctx context.Context) error {
+	if len(w.pending) == 0 {
+		return nil
+	}
+	batch := w.pending
+	w.pending = w.pending[:0]
+
+	affected, err := w.execBatch(ctx, batch)
+	if err != nil {
+		if !isRejectable(err) || ctx.Err() != nil {
+			return err
+		}
+		log.Printf("WARN: %s: batch of %d rows failed (%v); retrying row by row", w.target, len(batch), err)
+		if affected, err = w.execRows(ctx, batch); err != nil {
+			return err
+		}
+	} else {
+		w.written += int64(len(batch))
+	}
+	w.affected += affected
+	w.lastKey = batch[len(batch)-1].key
+
+	if w.onCommit != nil {
+		return w.onCommit(ctx, w.lastKey, w.done())
+	}
+	return nil
+}
+
+// done is the number of rows committed or rejected.
+func (w *batchWriter) done() int64 {
+	return w.written + w.rejected
+}
+
+// close flushes what is left and logs the rejects, if any.
+func (w *batchWriter) close(ctx context.Context) error {
+	if err := w.flush(ctx); err != nil {
+		return err
+	}
+	if w.rejected > 0 {
+		log.Printf("WARN: %s: %d rows rejected; see migration_reject WHERE phase = '%s' AND target = '%s'",
+			w.target, w.rejected, w.phase, w.target)
+	}
+	return nil
+}
+
+// execBatch runs every row of batch in one transaction.
+func (w *batchWriter) execBatch(ctx context.Context, batch []batchRow) (int64, error) {
+	tx, err := w.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("begin tx (%s): %w", w.target, err)
+	}
+	defer tx.Rollback()
+
+	stmts := make([]*sql.Stmt, len(w.queries))
+	for i, q := range w.queries {
+		if stmts[i], err = tx.PrepareContext(ctx, q); err != nil {
+			return 0, fmt.Errorf("prepare %s: %w", w.target, err)
+		}
+		defer stmts[i].Close()
+	}
+
+	var affected int64
+	for _, r := range batch {
+		n, err := execRow(ctx, stmts, r.args)
+		if err != nil {
+			return 0, fmt.Errorf("%s key=%d: %w", w.target, r.key, err)
+		}
+		affected += n
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit %s: %w", w.target, err)
+	}
+	return affected, nil
+}
+
+// execRows replays batch one row per transaction and quarantines the rows
+// that fail.
+func (w *batchWriter) execRows(ctx context.Context, batch []batchRow) (int64, error) {
+	var affected int64
+	for _, r := range batch {
+		n, err := w.execBatch(ctx, []batchRow{r})
+		switch {
+		case err == nil:
+			w.written++
+			affected += n
+		case isRejectable(err) && ctx.Err() == nil:
+			if err := w.reject(ctx, r, err); err != nil {
+				return affected, err
+			}
+		default:
+			return affected, err
+		}
+	}
+	return affected, nil
+}
+
+func execRow(ctx context.Context, stmts []*sql.Stmt, args []interface{}) (int64, error) {
+	var n int64
+	for _, stmt := range stmts {
+		res, err := stmt.ExecContext(ctx, args...)
+		if err != nil {
+			return 0, err
+		}
+		n, _ = res.RowsAffected()
+	}
+	return n, nil
+}
+
+// reject writes one failed row to migration_reject.
+func (w *batchWriter) reject(ctx context.Context, r batchRow, cause error) error {
+	row := make(map[string]interface{}, len(r.args))
+	for i, v := range r.args {
+		name := fmt.Sprintf("$%d", i+1)
+		if i < len(w.columns) {
+			name = w.columns[i]
+		}
+		row[name] = jsonValue(v)
+	}
+	source, err := json.Marshal(row)
+	if err != nil {
+		return fmt.Errorf("encode rejected %s row key=%d: %w", w.target, r.key, err)
+	}
+
+	var key interface{}
+	if r.key != 0 {
+		key = r.key
+	}
+	if _, err := w.db.ExecContext(ctx, `
+		INSERT INTO migration_reject (phase, target, source_key, error, source_row)
+		VALUES ($1, $2, $3, $4, $5)
+	`, w.phase, w.target, key, cause.Error(), source); err != nil {
+		return fmt.Errorf("insert migration_reject %s key=%d: %w", w.target, r.key, err)
+	}
+	w.rejected++
+	run.addRejects(1)
+	log.Printf("WARN: %s: rejected key=%d: %v", w.target, r.key, cause)
+	return nil
+}
+
+// jsonValue turns a loader arg (sql.Null*, pq.Array, []byte, ...) into the
+// plain value it is sent to Postgres as, so it encodes as readable JSON.
+func jsonValue(v interface{}) interface{} {
+	if valuer, ok := v.(driver.Valuer); ok {
+		if dv, err := valuer.Value(); err == nil {
+			v = dv
+		}
+	}
+	if b, ok := v.([]byte); ok {
+		return string(b)
+	}
+	return v
+}
+
+// isRejectable reports whether err is a per-row data problem: SQLSTATE class
+// 22 (data exception) or 23 (integrity constraint violation).
+func isRejectable(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	class := pqErr.Code.Class()
+	return class == "22" || class == "23"
+}
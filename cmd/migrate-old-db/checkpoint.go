@@ -0,0 +1,119 @@
+// cmd/migrate-old-db/checkpoint.go
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+)
+
+// checkpointEvery is how often (in rows) a streaming step persists its last
+// committed key to migration_checkpoint.
+const checkpointEvery = 10000
+
+// stepCheckpoint tracks one (phase, step) row of migration_checkpoint.
+//
+// Steps that stream ORDER BY key save() as their batches commit.
+// With -resume, a rerun continues after lastKey; without it the checkpoint is
+// reset and the step starts from zero.
+type stepCheckpoint struct {
+	db        *sql.DB
+	phase     string
+	step      string
+	lastKey   int64
+	rowsDone  int64
+	completed bool
+}
+
+// startCheckpoint loads (with -resume) or resets (without) the checkpoint for
+// phase/step. Only call it on real runs; it writes to the new DB.
+func startCheckpoint(ctx context.Context, newDB *sql.DB, phase, step string) (*stepCheckpoint, error) {
+	cp := &stepCheckpoint{db: newDB, phase: phase, step: step}
+
+	if !*resume {
+		if _, err := newDB.ExecContext(ctx, `
+			DELETE FROM migration_checkpoint
+			WHERE phase = $1 AND step = $2
+		`, phase, step); err != nil {
+			return nil, fmt.Errorf("reset checkpoint %s/%s: %w", phase, step, err)
+		}
+		return cp, nil
+	}
+
+	var lastKey sql.NullInt64
+	err := newDB.QueryRowContext(ctx, `
+		SELECT last_key, rows_done, completed_at IS NOT NULL
+		FROM migration_checkpoint
+		WHERE phase = $1 AND step = $2
+	`, phase, step).Scan(&lastKey, &cp.rowsDone, &cp.completed)
+	switch {
+	case err == sql.ErrNoRows:
+		log.Printf("checkpoint %s/%s: none found, starting from the beginning", phase, step)
+		return cp, nil
+	case err != nil:
+		return nil, fmt.Errorf("load checkpoint %s/%s: %w", phase, step, err)
+	}
+	cp.lastKey = lastKey.Int64
+
+	if cp.completed {
+		log.Printf("checkpoint %s/%s: already completed (%d rows, last key %d)", phase, step, cp.rowsDone, cp.lastKey)
+	} else {
+		log.Printf("checkpoint %s/%s: resuming after key %d (%d rows already done)", phase, step, cp.lastKey, cp.rowsDone)
+	}
+	return cp, nil
+}
+
+// save records key as the last committed key.
+func (cp *stepCheckpoint) save(ctx context.Context, key, rowsDone int64) error {
+	return cp.write(ctx, key, rowsDone, false)
+}
+
+// complete marks the step as finished, so a -resume run skips it.
+func (cp *stepCheckpoint) complete(ctx context.Context, key, rowsDone int64) error {
+	return cp.write(ctx, key, rowsDone, true)
+}
+
+func (cp *stepCheckpoint) write(ctx context.Context, key, rowsDone int64, completed bool) error {
+	if _, err := cp.db.ExecContext(ctx, `
+		INSERT INTO migration_checkpoint (phase, step, last_key, rows_done, completed_at, updated_at)
+		VALUES ($1, $2, $3, $4, CASE WHEN $5 THEN now() END, now())
+		ON CONFLICT (phase, step) DO UPDATE
+		SET last_key     = EXCLUDED.last_key,
+		    rows_done    = EXCLUDED.rows_done,
+		    completed_at = EXCLUDED.completed_at,
+		    updated_at   = now()
+	`, cp.phase, cp.step, key, rowsDone, completed); err != nil {
+		return fmt.Errorf("save checkpoint %s/%s key=%d: %w", cp.phase, cp.step, key, err)
+	}
+	cp.lastKey = key
+	cp.rowsDone = rowsDone
+	cp.completed = completed
+	return nil
+}
+
+// saveOnError persists the last committed key after a failed row, so -resume
+// restarts right at the failing row. Errors are only logged; the caller is
+// already returning the original error.
+func (cp *stepCheckpoint) saveOnError(ctx context.Context, key, rowsDone int64) {
+	if rowsDone == cp.rowsDone {
+		return
+	}
+	if err := cp.save(ctx, key, rowsDone); err != nil {
+		log.Printf("WARN: %v", err)
+		return
+	}
+	log.Printf("checkpoint %s/%s: saved last committed key %d; rerun with -resume to continue", cp.phase, cp.step, key)
+}
+
+// saveEvery returns a batchWriter.onCommit that saves the checkpoint once
+// checkpointEvery more rows are done. base is the row count of the runs being
+// resumed.
+func (cp *stepCheckpoint) saveEvery(base int64) func(ctx context.Context, lastKey, done int64) error {
+	return func(ctx context.Context, lastKey, done int64) error {
+		if base+done-cp.rowsDone < checkpointEvery {
+			return nil
+		}
+		return cp.save(ctx, lastKey, base+done)
+	}
+}
@@ -0,0 +1,232 @@
+// cmd/migrate-old-db/copy_load.go
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Write paths selected with -mode.
+const (
+	modeRow  = "row"
+	modeCopy = "copy"
+)
+
+const copyProgressEvery = 500000
+
+// loadJob describes how one new table is filled from an old source query.
+//
+// In -mode=copy the rows are streamed with COPY into an unlogged staging table
+// shaped like the target, then merged with a single INSERT ... SELECT ... ON
+// CONFLICT. Junction jobs are also run row by row (runRowJob) and split into
+// TitleID ranges with -workers.
+type loadJob struct {
+	target     string   // new table, e.g. "title_country"
+	columns    []string // target columns, in the order scan returns values
+	keyColumns []string // ON CONFLICT target
+	noUpdate   []string // non-key columns left untouched on conflict
+	extraSet   string   // extra SET clause on conflict, e.g. "updated_at = now()"
+	doNothing  bool     // ON CONFLICT DO NOTHING instead of updating
+
+	// requireTitle / requirePerson drop rows whose title_id (first value) or
+	// person_id (second value) is not in the new DB.
+	requireTitle  bool
+	requirePerson bool
+
+	srcQuery    string
+	srcArgs     []interface{}
+	rangeSource string // old table TitleID ranges are computed on (-workers)
+	// scan returns the values for one old row, or keep=false to skip it
+	// (e.g. no ID mapping). Skipped rows are counted, not written.
+	scan func(rows *sql.Rows) (values []interface{}, keep bool, err error)
+
+	// stagingSuffix keeps concurrent workers on separate staging tables.
+	stagingSuffix string
+}
+
+func (j loadJob) stagingTable() string {
+	return "staging_" + j.target + j.stagingSuffix
+}
+
+// conflictClause is the ON CONFLICT part shared by the row and COPY paths.
+func (j loadJob) conflictClause() string {
+	keys := strings.Join(j.keyColumns, ", ")
+	if j.doNothing {
+		return fmt.Sprintf("ON CONFLICT (%s) DO NOTHING", keys)
+	}
+
+	skip := make(map[string]bool)
+	for _, c := range j.keyColumns {
+		skip[c] = true
+	}
+	for _, c := range j.noUpdate {
+		skip[c] = true
+	}
+	var sets []string
+	for _, c := range j.columns {
+		if !skip[c] {
+			sets = append(sets, fmt.Sprintf("%s = EXCLUDED.%s", c, c))
+		}
+	}
+	if j.extraSet != "" {
+		sets = append(sets, j.extraSet)
+	}
+	return fmt.Sprintf("ON CONFLICT (%s) DO UPDATE SET\n\t%s", keys, strings.Join(sets, ",\n\t"))
+}
+
+// insertSQL is the single-row upsert used by the row path.
+func (j loadJob) insertSQL() string {
+	placeholders := make([]string, len(j.columns))
+	for i := range placeholders {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+	}
+	return fmt.Sprintf("INSERT INTO %s (%s)\nVALUES (%s)\n%s",
+		j.target, strings.Join(j.columns, ", "), strings.Join(placeholders, ", "), j.conflictClause())
+}
+
+// mergeSQL builds the set-based upsert from the staging table. DISTINCT ON
+// keeps one row per key, since a single INSERT can't update a row twice.
+func (j loadJob) mergeSQL() string {
+	cols := strings.Join(j.columns, ", ")
+	keys := strings.Join(j.keyColumns, ", ")
+
+	var filters []string
+	if j.requireTitle {
+		filters = append(filters, `EXISTS (SELECT 1 FROM title t WHERE t.id = s.title_id)`)
+	}
+	if j.requirePerson {
+		filters = append(filters, `EXISTS (SELECT 1 FROM person p WHERE p.id = s.person_id)`)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "INSERT INTO %s (%s)\n", j.target, cols)
+	fmt.Fprintf(&b, "SELECT DISTINCT ON (%s) %s\n", keys, cols)
+	fmt.Fprintf(&b, "FROM %s s\n", j.stagingTable())
+	if len(filters) > 0 {
+		fmt.Fprintf(&b, "WHERE %s\n", strings.Join(filters, " AND "))
+	}
+	fmt.Fprintf(&b, "ORDER BY %s\n", keys)
+	b.WriteString(j.conflictClause())
+	return b.String()
+}
+
+// runCopyJob runs one copy load in a single transaction on the new DB and
+// reports COPY and merge throughput. Copied and skipped rows are added to
+// progress as they stream.
+func runCopyJob(ctx context.Context, oldDB, newDB *sql.DB, job loadJob, progress *jobProgress) error {
+	staging := job.stagingTable()
+	log.Printf("copyLoad %s: COPY into unlogged %s, then merge", job.target, staging)
+
+	rows, err := oldDB.QueryContext(ctx, job.srcQuery, job.srcArgs...)
+	if err != nil {
+		return fmt.Errorf("query source for %s: %w", job.target, err)
+	}
+	defer rows.Close()
+
+	tx, err := newDB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx (%s copy): %w", job.target, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf(`DROP TABLE IF EXISTS %s`, staging)); err != nil {
+		return fmt.Errorf("drop %s: %w", staging, err)
+	}
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf(
+		`CREATE UNLOGGED TABLE %s AS SELECT %s FROM %s WITH NO DATA`,
+		staging, strings.Join(job.columns, ", "), job.target,
+	)); err != nil {
+		return fmt.Errorf("create %s: %w", staging, err)
+	}
+
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn(staging, job.columns...))
+	if err != nil {
+		return fmt.Errorf("prepare COPY %s: %w", staging, err)
+	}
+
+	copyStart := time.Now()
+	var copied, skipped int64
+	for rows.Next() {
+		values, keep, err := job.scan(rows)
+		if err != nil {
+			stmt.Close()
+			return err
+		}
+		if !keep {
+			skipped++
+			progress.add(0, 1)
+			continue
+		}
+		if _, err := stmt.ExecContext(ctx, values...); err != nil {
+			stmt.Close()
+			return fmt.Errorf("COPY row into %s: %w", staging, err)
+		}
+		copied++
+		progress.add(1, 0)
+		if copied%copyProgressEvery == 0 {
+			logProgress(job.target, copied, skipped, copyStart,
+				"copyLoad %s: copied %d rows (%.0f rows/s)", staging, copied, rowsPerSecond(copied, time.Since(copyStart)))
+		}
+	}
+	if err := rows.Err(); err != nil {
+		stmt.Close()
+		return fmt.Errorf("iterate source for %s: %w", job.target, err)
+	}
+	// An Exec without arguments flushes the COPY buffer.
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		stmt.Close()
+		return fmt.Errorf("flush COPY %s: %w", staging, err)
+	}
+	if err := stmt.Close(); err != nil {
+		return fmt.Errorf("close COPY %s: %w", staging, err)
+	}
+	copyDur := time.Since(copyStart)
+	log.Printf("copyLoad %s: copied %d rows in %s (%.0f rows/s), %d skipped before COPY",
+		staging, copied, copyDur, rowsPerSecond(copied, copyDur), skipped)
+
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf(`ANALYZE %s`, staging)); err != nil {
+		return fmt.Errorf("analyze %s: %w", staging, err)
+	}
+
+	mergeStart := time.Now()
+	res, err := tx.ExecContext(ctx, job.mergeSQL())
+	if err != nil {
+		return fmt.Errorf("merge %s into %s: %w", staging, job.target, err)
+	}
+	merged, _ := res.RowsAffected()
+	mergeDur := time.Since(mergeStart)
+
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf(`DROP TABLE %s`, staging)); err != nil {
+		return fmt.Errorf("drop %s: %w", staging, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit %s copy: %w", job.target, err)
+	}
+
+	total := copyDur + mergeDur
+	log.Printf("copyLoad %s: merged %d rows in %s (%.0f rows/s); %d staged rows not merged (duplicate key, filtered out or already present)",
+		staging, merged, mergeDur, rowsPerSecond(merged, mergeDur), copied-merged)
+	// Under runJunctionJob the step is the whole job, reported once all
+	// workers are done.
+	done := logStepDone
+	if progress != nil {
+		done = logProgress
+	}
+	done(job.target, copied, skipped, copyStart, "--- Done %s [copy]: %d rows in %s (%.0f rows/s overall) ---",
+		staging, copied, total, rowsPerSecond(copied, total))
+	return nil
+}
+
+// rowsPerSecond is the throughput figure logged by both write paths.
+func rowsPerSecond(n int64, d time.Duration) float64 {
+	if d <= 0 {
+		return 0
+	}
+	return float64(n) / d.Seconds()
+}
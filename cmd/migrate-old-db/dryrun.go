@@ -0,0 +1,453 @@
+// cmd/migrate-old-db/dryrun.go
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// -dry-run modes.
+const (
+	dryRunDiff  = "diff"  // run the phases against a scratch copy and diff it
+	dryRunCount = "count" // the phases' own read-and-count paths only
+)
+
+// diffIgnoreColumns are bumped by every upsert, so a change in them alone is
+// a no-op.
+var diffIgnoreColumns = map[string]bool{
+	"updated_at":      true,
+	"last_checked_at": true,
+}
+
+// plannedNewTables lists the new tables the planned phases name in
+// phaseSchema; they are the ones the scratch schema has to hold.
+func plannedNewTables(plan []phaseSpec) []string {
+	seen := make(map[string]bool)
+	var tables []string
+	for _, p := range plan {
+		for _, u := range phaseSchema[p.name] {
+			if !u.old && !seen[u.table] {
+				seen[u.table] = true
+				tables = append(tables, u.table)
+			}
+		}
+	}
+	sort.Strings(tables)
+	return tables
+}
+
+// runDryRunDiff copies the tables the plan touches into a scratch schema, runs
+// every phase for real against it, reports inserts, updates and no-ops per
+// table compared with public, and drops the scratch schema again.
+func runDryRunDiff(ctx context.Context, oldDB, newDB *sql.DB, plan []phaseSpec) error {
+	tables := plannedNewTables(plan)
+	schema := fmt.Sprintf("dryrun_%d", time.Now().Unix())
+
+	log.Printf("=== DRY-RUN (diff): copying %d tables into scratch schema %s ===", len(tables), schema)
+	defer func() {
+		// ctx may already be cancelled; the scratch schema still has to go.
+		if _, err := newDB.ExecContext(context.Background(),
+			`DROP SCHEMA IF EXISTS `+pq.QuoteIdentifier(schema)+` CASCADE`); err != nil {
+			log.Printf("WARN: drop scratch schema %s: %v", schema, err)
+			return
+		}
+		log.Printf("DRY-RUN (diff): dropped scratch schema %s", schema)
+	}()
+	if err := createScratchSchema(ctx, newDB, schema, tables); err != nil {
+		return err
+	}
+
+	scratchDSN, err := dsnWithSearchPath(*newDSN, schema)
+	if err != nil {
+		return err
+	}
+	scratchDB, err := sql.Open("postgres", scratchDSN)
+	if err != nil {
+		return fmt.Errorf("open scratch DB: %w", err)
+	}
+	defer scratchDB.Close()
+
+	if err := runPhases(ctx, oldDB, scratchDB, plan, false); err != nil {
+		return err
+	}
+
+	log.Printf("=== DRY-RUN (diff): changes the run would make to public ===")
+	for _, table := range tables {
+		if err := diffScratchTable(ctx, newDB, schema, table, *dryRunCSV); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dsnWithSearchPath adds search_path to a URL or key=value DSN; lib/pq sends
+// it as a run-time parameter, so every pooled connection writes to schema.
+func dsnWithSearchPath(dsn, schema string) (string, error) {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			return "", fmt.Errorf("parse -new DSN: %w", err)
+		}
+		q := u.Query()
+		q.Set("search_path", schema)
+		u.RawQuery = q.Encode()
+		return u.String(), nil
+	}
+	return dsn + " search_path=" + schema, nil
+}
+
+// createScratchSchema clones each table (columns, defaults, identity, checks,
+// primary and unique keys under their public names, so ON CONFLICT ON
+// CONSTRAINT still resolves) and copies its rows. Foreign keys and plain
+// indexes are left out.
+func createScratchSchema(ctx context.Context, newDB *sql.DB, schema string, tables []string) error {
+	s := pq.QuoteIdentifier(schema)
+	if _, err := newDB.ExecContext(ctx, `CREATE SCHEMA `+s); err != nil {
+		return fmt.Errorf("create scratch schema: %w", err)
+	}
+
+	for _, table := range tables {
+		src := "public." + pq.QuoteIdentifier(table)
+		dst := s + "." + pq.QuoteIdentifier(table)
+
+		if _, err := newDB.ExecContext(ctx, fmt.Sprintf(
+			`CREATE TABLE %s (LIKE %s INCLUDING DEFAULTS INCLUDING IDENTITY INCLUDING GENERATED INCLUDING CONSTRAINTS)`,
+			dst, src)); err != nil {
+			return fmt.Errorf("clone %s: %w", table, err)
+		}
+		res, err := newDB.ExecContext(ctx, fmt.Sprintf(`INSERT INTO %s SELECT * FROM %s`, dst, src))
+		if err != nil {
+			return fmt.Errorf("copy %s: %w", table, err)
+		}
+		n, _ := res.RowsAffected()
+
+		if err := cloneUniqueKeys(ctx, newDB, schema, table); err != nil {
+			return err
+		}
+		if err := cloneSequences(ctx, newDB, schema, table); err != nil {
+			return err
+		}
+		log.Printf("DRY-RUN (diff): %s.%s: %d rows copied", schema, table, n)
+	}
+	return nil
+}
+
+// indexTableRe matches the table part of a pg_get_indexdef result.
+var indexTableRe = regexp.MustCompile(`^(CREATE UNIQUE INDEX \S+ ON (?:ONLY )?)\S+ `)
+
+func cloneUniqueKeys(ctx context.Context, newDB *sql.DB, schema, table string) error {
+	rows, err := newDB.QueryContext(ctx, `
+		SELECT 'ALTER TABLE ' || $2 || ' ADD CONSTRAINT ' || quote_ident(c.conname) || ' ' || pg_get_constraintdef(c.oid)
+		FROM pg_constraint c
+		WHERE c.conrelid = $1::regclass AND c.contype IN ('p', 'u')
+		UNION ALL
+		SELECT pg_get_indexdef(i.indexrelid)
+		FROM pg_index i
+		WHERE i.indrelid = $1::regclass AND i.indisunique
+		  AND NOT EXISTS (SELECT 1 FROM pg_constraint c WHERE c.conindid = i.indexrelid)
+	`, "public."+pq.QuoteIdentifier(table), pq.QuoteIdentifier(schema)+"."+pq.QuoteIdentifier(table))
+	if err != nil {
+		return fmt.Errorf("read keys of %s: %w", table, err)
+	}
+	var stmts []string
+	for rows.Next() {
+		var stmt string
+		if err := rows.Scan(&stmt); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan key of %s: %w", table, err)
+		}
+		// pg_get_indexdef only qualifies the table when public is not on the
+		// search_path; point it at the scratch copy either way.
+		if strings.HasPrefix(stmt, "CREATE UNIQUE INDEX") {
+			stmt = indexTableRe.ReplaceAllString(stmt, "${1}"+pq.QuoteIdentifier(schema)+"."+pq.QuoteIdentifier(table)+" ")
+		}
+		stmts = append(stmts, stmt)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate keys of %s: %w", table, err)
+	}
+
+	for _, stmt := range stmts {
+		if _, err := newDB.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("clone key of %s: %w", table, err)
+		}
+	}
+	return nil
+}
+
+// cloneSequences moves identity sequences past the copied rows and gives
+// serial columns their own scratch sequence, so the dry-run neither collides
+// with copied IDs nor advances the public sequences.
+func cloneSequences(ctx context.Context, newDB *sql.DB, schema, table string) error {
+	rows, err := newDB.QueryContext(ctx, `
+		SELECT column_name, is_identity = 'YES'
+		FROM information_schema.columns
+		WHERE table_schema = $1 AND table_name = $2
+		  AND (is_identity = 'YES' OR column_default LIKE 'nextval(%')
+	`, schema, table)
+	if err != nil {
+		return fmt.Errorf("read sequences of %s: %w", table, err)
+	}
+	type seqColumn struct {
+		name     string
+		identity bool
+	}
+	var cols []seqColumn
+	for rows.Next() {
+		var c seqColumn
+		if err := rows.Scan(&c.name, &c.identity); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan sequence of %s: %w", table, err)
+		}
+		cols = append(cols, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate sequences of %s: %w", table, err)
+	}
+
+	qt := pq.QuoteIdentifier(schema) + "." + pq.QuoteIdentifier(table)
+	for _, c := range cols {
+		col := pq.QuoteIdentifier(c.name)
+		seq := pq.QuoteIdentifier(schema) + "." + pq.QuoteIdentifier(table+"_"+c.name+"_seq")
+		var stmts []string
+		if c.identity {
+			seq = fmt.Sprintf("pg_get_serial_sequence('%s', '%s')", qt, c.name)
+		} else {
+			stmts = append(stmts,
+				fmt.Sprintf(`CREATE SEQUENCE %s OWNED BY %s.%s`, seq, qt, col),
+				fmt.Sprintf(`ALTER TABLE %s ALTER COLUMN %s SET DEFAULT nextval('%s')`, qt, col, seq))
+			seq = "'" + seq + "'"
+		}
+		stmts = append(stmts, fmt.Sprintf(`SELECT setval(%s, COALESCE(MAX(%s), 0) + 1, false) FROM %s`, seq, col, qt))
+		for _, stmt := range stmts {
+			if _, err := newDB.ExecContext(ctx, stmt); err != nil {
+				return fmt.Errorf("clone sequence %s.%s: %w", table, c.name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// diffScratchTable logs what the dry-run changed in one table: rows inserted,
+// rows deleted, rows updated (with the columns that changed) and rows left as
+// they were. Rows are matched on the primary key; tables without one are
+// compared as whole rows, so an update shows up as one insert plus one delete.
+// With csvDir set the changed rows are written to csvDir/<table>.csv.
+func diffScratchTable(ctx context.Context, newDB *sql.DB, schema, table, csvDir string) error {
+	columns, keys, err := tableColumns(ctx, newDB, table)
+	if err != nil {
+		return err
+	}
+	oldT := "public." + pq.QuoteIdentifier(table)
+	newT := pq.QuoteIdentifier(schema) + "." + pq.QuoteIdentifier(table)
+
+	var compared []string
+	for _, c := range columns {
+		if !diffIgnoreColumns[c] && !contains(keys, c) {
+			compared = append(compared, c)
+		}
+	}
+
+	var (
+		inserts, deletes, updates, matched int64
+		changed                            = make([]int64, len(compared))
+		csvQuery                           string
+	)
+	if len(keys) == 0 {
+		cols := quoteColumns(columns)
+		err = newDB.QueryRowContext(ctx, fmt.Sprintf(`
+			SELECT (SELECT COUNT(*) FROM (SELECT %[1]s FROM %[2]s EXCEPT ALL SELECT %[1]s FROM %[3]s) x),
+			       (SELECT COUNT(*) FROM (SELECT %[1]s FROM %[3]s EXCEPT ALL SELECT %[1]s FROM %[2]s) x),
+			       (SELECT COUNT(*) FROM %[2]s)
+		`, cols, newT, oldT)).Scan(&inserts, &deletes, &matched)
+		matched -= inserts
+		csvQuery = fmt.Sprintf(`
+			SELECT 'insert', %[4]s FROM (SELECT %[1]s FROM %[2]s EXCEPT ALL SELECT %[1]s FROM %[3]s) x
+			UNION ALL
+			SELECT 'delete', %[4]s FROM (SELECT %[1]s FROM %[3]s EXCEPT ALL SELECT %[1]s FROM %[2]s) x
+		`, cols, newT, oldT, textColumns("x", columns))
+	} else {
+		join := make([]string, len(keys))
+		for i, k := range keys {
+			join[i] = fmt.Sprintf("n.%[1]s = o.%[1]s", pq.QuoteIdentifier(k))
+		}
+		on := strings.Join(join, " AND ")
+
+		differs := make([]string, len(compared))
+		for i, c := range compared {
+			differs[i] = fmt.Sprintf("n.%[1]s IS DISTINCT FROM o.%[1]s", pq.QuoteIdentifier(c))
+		}
+		anyDiffers := "false"
+		if len(differs) > 0 {
+			anyDiffers = strings.Join(differs, " OR ")
+		}
+		perColumn := ""
+		for _, d := range differs {
+			perColumn += ", COUNT(*) FILTER (WHERE " + d + ")"
+		}
+
+		dest := []interface{}{&inserts, &deletes, &updates, &matched}
+		for i := range changed {
+			dest = append(dest, &changed[i])
+		}
+		err = newDB.QueryRowContext(ctx, fmt.Sprintf(`
+			SELECT (SELECT COUNT(*) FROM %[1]s n WHERE NOT EXISTS (SELECT 1 FROM %[2]s o WHERE %[3]s)),
+			       (SELECT COUNT(*) FROM %[2]s o WHERE NOT EXISTS (SELECT 1 FROM %[1]s n WHERE %[3]s)),
+			       COUNT(*) FILTER (WHERE %[4]s),
+			       COUNT(*)%[5]s
+			FROM %[1]s n
+			JOIN %[2]s o ON %[3]s
+		`, newT, oldT, on, anyDiffers, perColumn)).Scan(dest...)
+		csvQuery = fmt.Sprintf(`
+			SELECT 'insert', %[5]s FROM %[1]s n WHERE NOT EXISTS (SELECT 1 FROM %[2]s o WHERE %[3]s)
+			UNION ALL
+			SELECT 'update', %[5]s FROM %[1]s n JOIN %[2]s o ON %[3]s WHERE %[4]s
+			UNION ALL
+			SELECT 'delete', %[6]s FROM %[2]s o WHERE NOT EXISTS (SELECT 1 FROM %[1]s n WHERE %[3]s)
+		`, newT, oldT, on, anyDiffers, textColumns("n", columns), textColumns("o", columns))
+	}
+	if err != nil {
+		return fmt.Errorf("diff %s: %w", table, err)
+	}
+
+	var changedCols []string
+	for i, c := range compared {
+		if changed[i] > 0 {
+			changedCols = append(changedCols, fmt.Sprintf("%s=%d", c, changed[i]))
+		}
+	}
+	detail := ""
+	if len(changedCols) > 0 {
+		detail = " (" + strings.Join(changedCols, ", ") + ")"
+	}
+	log.Printf("DRY-RUN %-28s insert=%-8d update=%-8d delete=%-8d no-op=%d%s",
+		table, inserts, updates, deletes, matched-updates, detail)
+
+	if csvDir == "" || inserts+updates+deletes == 0 {
+		return nil
+	}
+	return writeDiffCSV(ctx, newDB, filepath.Join(csvDir, table+".csv"), columns, csvQuery)
+}
+
+// writeDiffCSV writes one row per change: the change kind, then the would-be
+// row (the public row for deletes). NULL is written as an empty field.
+func writeDiffCSV(ctx context.Context, newDB *sql.DB, path string, columns []string, query string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create %s: %w", filepath.Dir(path), err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create %s: %w", path, err)
+	}
+	defer f.Close()
+
+	rows, err := newDB.QueryContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("select changed rows for %s: %w", path, err)
+	}
+	defer rows.Close()
+
+	w := csv.NewWriter(f)
+	if err := w.Write(append([]string{"change"}, columns...)); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	values := make([]sql.NullString, len(columns)+1)
+	dest := make([]interface{}, len(values))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	record := make([]string, len(values))
+	var n int64
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return fmt.Errorf("scan changed row for %s: %w", path, err)
+		}
+		for i, v := range values {
+			record[i] = v.String
+		}
+		if err := w.Write(record); err != nil {
+			return fmt.Errorf("write %s: %w", path, err)
+		}
+		n++
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate changed rows for %s: %w", path, err)
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	log.Printf("DRY-RUN %s: wrote %d changed rows to %s", filepath.Base(path), n, path)
+	return f.Close()
+}
+
+// tableColumns returns the columns of public.table in order, and its primary
+// key columns.
+func tableColumns(ctx context.Context, newDB *sql.DB, table string) (columns, keys []string, err error) {
+	rows, err := newDB.QueryContext(ctx, `
+		SELECT a.attname,
+		       EXISTS (SELECT 1 FROM pg_index i
+		               WHERE i.indrelid = a.attrelid AND i.indisprimary AND a.attnum = ANY (i.indkey))
+		FROM pg_attribute a
+		WHERE a.attrelid = $1::regclass AND a.attnum > 0 AND NOT a.attisdropped
+		ORDER BY a.attnum
+	`, "public."+pq.QuoteIdentifier(table))
+	if err != nil {
+		return nil, nil, fmt.Errorf("read columns of %s: %w", table, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		var isKey bool
+		if err := rows.Scan(&name, &isKey); err != nil {
+			return nil, nil, fmt.Errorf("scan column of %s: %w", table, err)
+		}
+		columns = append(columns, name)
+		if isKey {
+			keys = append(keys, name)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("iterate columns of %s: %w", table, err)
+	}
+	return columns, keys, nil
+}
+
+func quoteColumns(columns []string) string {
+	out := make([]string, len(columns))
+	for i, c := range columns {
+		out[i] = pq.QuoteIdentifier(c)
+	}
+	return strings.Join(out, ", ")
+}
+
+func textColumns(alias string, columns []string) string {
+	out := make([]string, len(columns))
+	for i, c := range columns {
+		out[i] = alias + "." + pq.QuoteIdentifier(c) + "::text"
+	}
+	return strings.Join(out, ", ")
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -4,8 +4,11 @@ import (
 	"context"
 	"database/sql"
 	"flag"
+	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	_ "github.com/lib/pq"
@@ -14,30 +17,112 @@ import (
 var (
 	oldDSN = flag.String("old", "", "Postgres DSN for OLD database (mediadb)")
 	newDSN = flag.String("new", "", "Postgres DSN for NEW database (movies3db)")
-	phase  = flag.String("phase", "refs", "Migration phase (refs | core-persons | core-title | junctions-country | junctions-language | junctions-genre | junctions-alias | junctions-certificate | junctions)")
-	dryRun = flag.Bool("dry-run", false, "if set, do NOT write to new DB; just read and count")
+	phase  = flag.String("phase", "refs", "Migration phase ("+phaseNames()+"); append + to also run every phase depending on it, e.g. core-title+")
+	dryRun = flag.Bool("dry-run", false, "if set, do NOT write to the new DB's tables; see -dry-run-mode")
+	mode   = flag.String("mode", modeRow, "write path: row (one upsert per row) | copy (COPY into unlogged staging tables, then one set-based upsert; core-persons, core-title and the country/language/genre/certificate/cast junctions)")
+	plan   = flag.Bool("plan", false, "print the execution order -phase resolves to and exit, without connecting to either DB")
+	resume = flag.Bool("resume", false, "core-persons / core-title: continue after the last key in migration_checkpoint instead of starting over")
+
+	preflight = flag.Bool("preflight", true, "check every table and column the planned phases use against information_schema on both DBs before writing anything")
+
+	workers = flag.Int("workers", 1, "country/language/genre/certificate/cast junctions: split the source table into N TitleID ranges and load them concurrently, each on its own connection")
+
+	batchSize = flag.Int("batch-size", 1000, "row-mode loads: rows per transaction; a failing batch is retried row by row and the rows that still fail go to migration_reject")
+
+	verifyOut    = flag.String("verify-out", "verify_report.json", "verify: path of the JSON reconciliation report")
+	verifySample = flag.Int("verify-sample", 1000, "verify: number of random titles and persons whose fields are checksummed against the old rows")
+
+	checkFiles = flag.Bool("check-files", false, "media-files: stat each title folder and set media_file.is_missing when it no longer exists")
+	pathMap    = flag.String("path-map", "", `with -check-files: legacy folder prefixes to stat under a local path, as "FROM=TO" pairs separated by ';' (e.g. "D:\Movies=/mnt/movies"); matching ignores case and separator style`)
+
+	dryRunMode = flag.String("dry-run-mode", dryRunDiff, "with -dry-run: diff (run the phases against a scratch copy of the tables they touch and report inserts/updates/no-ops against public) | count (only read and count)")
+	dryRunCSV  = flag.String("dry-run-csv", "", "with -dry-run-mode=diff: directory to write <table>.csv files of the inserted, updated and deleted rows to")
+
+	overridesPath = flag.String("overrides", "", "YAML file pinning old IDs to a new ID or name per entity (see overrides.go); applied by refs and every phase that loads id_map")
+
+	imdbTitleBasics = flag.String("imdb-title-basics", "", "imdb-ids: path of an IMDb title.basics.tsv(.gz) dump to match titles against on name+year")
+	imdbNameBasics  = flag.String("imdb-name-basics", "", "imdb-ids: path of an IMDb name.basics.tsv(.gz) dump to match persons against on name")
+
+	logFormat = flag.String("log-format", logFormatText, "text | json (one JSON object per line; progress lines carry phase, step, processed, skipped, rate and elapsed)")
 )
 
 func main() {
 	log.SetOutput(os.Stdout)
 	flag.Parse()
 
+	switch *logFormat {
+	case logFormatText:
+	case logFormatJSON:
+		useJSONLog()
+	default:
+		log.Printf("ERROR: unknown -log-format %q (want %s or %s)", *logFormat, logFormatText, logFormatJSON)
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	planned, err := resolvePlan(*phase)
+	if err != nil {
+		log.Printf("ERROR: %v", err)
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *plan {
+		printPlan(*phase, planned)
+		return
+	}
+
 	if *oldDSN == "" || *newDSN == "" {
 		log.Printf("ERROR: both -old and -new DSNs are required")
 		flag.Usage()
 		os.Exit(2)
 	}
 
-	ctx := context.Background()
+	if *mode != modeRow && *mode != modeCopy {
+		log.Printf("ERROR: unknown -mode %q (want %s or %s)", *mode, modeRow, modeCopy)
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if *dryRunMode != dryRunDiff && *dryRunMode != dryRunCount {
+		log.Printf("ERROR: unknown -dry-run-mode %q (want %s or %s)", *dryRunMode, dryRunDiff, dryRunCount)
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if *workers < 1 {
+		log.Printf("ERROR: -workers must be at least 1, got %d", *workers)
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if *batchSize < 1 {
+		log.Printf("ERROR: -batch-size must be at least 1, got %d", *batchSize)
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if *overridesPath != "" {
+		set, err := loadOverrides(*overridesPath)
+		if err != nil {
+			log.Printf("ERROR: %v", err)
+			os.Exit(2)
+		}
+		overrides = set
+	}
+
+	// Ctrl-C / SIGTERM cancels ctx, which stops every worker and rolls back
+	// their open transactions.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	log.Printf("Connecting to OLD DB: %s", *oldDSN)
+	log.Printf("Connecting to OLD DB: %s", redactDSN(*oldDSN))
 	oldDB, err := sql.Open("postgres", *oldDSN)
 	if err != nil {
 		log.Fatalf("open old DB: %v", err)
 	}
 	defer oldDB.Close()
 
-	log.Printf("Connecting to NEW DB: %s", *newDSN)
+	log.Printf("Connecting to NEW DB: %s", redactDSN(*newDSN))
 	newDB, err := sql.Open("postgres", *newDSN)
 	if err != nil {
 		log.Fatalf("open new DB: %v", err)
@@ -50,51 +135,58 @@ func main() {
 	if err := newDB.PingContext(ctx); err != nil {
 		log.Fatalf("ping new DB: %v", err)
 	}
+	if err := overrides.check(ctx, newDB); err != nil {
+		log.Fatalf("%v", err)
+	}
 
-	log.Printf("=== Starting migration phase=%q dryRun=%v ===", *phase, *dryRun)
-	start := time.Now()
-
-	var phaseErr error
-
-	switch *phase {
-	case "refs":
-		phaseErr = MigrateRefsPhase(ctx, oldDB, newDB, *dryRun)
-
-	case "core-persons":
-		// implemented earlier, not touched here
-		phaseErr = MigrateCorePersonsPhase(ctx, oldDB, newDB, *dryRun)
-
-	case "core-title":
-		phaseErr = MigrateCoreTitlesPhase(ctx, oldDB, newDB, *dryRun)
-
-	case "junctions":
-		// OPTIONAL umbrella phase if you still use it:
-		// basic + others, depending on how you wired it
-		phaseErr = MigrateJunctionsPhase(ctx, oldDB, newDB, *dryRun)
-
-	case "junctions-country":
-		phaseErr = MigrateJunctionsCountryPhase(ctx, oldDB, newDB, *dryRun)
-
-	case "junctions-language":
-		phaseErr = MigrateJunctionsLanguagePhase(ctx, oldDB, newDB, *dryRun)
+	// Every invocation gets a migration_run row, dry runs and failed
+	// preflights included. Without the table (schema not yet applied) the
+	// run goes on unrecorded, so preflight still lists every mismatch.
+	if err := run.begin(ctx, newDB, planned); err != nil {
+		log.Printf("WARN: %v; this run is not recorded in migration_run", err)
+	}
 
-	case "junctions-genre":
-		phaseErr = MigrateJunctionsGenrePhase(ctx, oldDB, newDB, *dryRun)
+	start := time.Now()
+	err = runMigration(ctx, oldDB, newDB, planned)
+	if ferr := run.finish(ctx, newDB, err); ferr != nil {
+		log.Printf("WARN: %v", ferr)
+	}
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
 
-	case "junctions-alias":
-		phaseErr = MigrateJunctionsAliasPhase(ctx, oldDB, newDB, *dryRun)
+	overrides.logReport()
 
-	case "junctions-certificate":
-		phaseErr = MigrateJunctionsCertificatePhase(ctx, oldDB, newDB, *dryRun)
+	log.Printf("=== Migration phase=%q completed successfully in %s ===",
+		*phase, time.Since(start).Truncate(time.Millisecond))
+}
 
-	default:
-		log.Fatalf("unknown phase %q", *phase)
+// runMigration checks the schema and runs the planned phases, in a scratch
+// schema for -dry-run-mode=diff.
+func runMigration(ctx context.Context, oldDB, newDB *sql.DB, planned []phaseSpec) error {
+	if *preflight {
+		if err := runPreflight(ctx, oldDB, newDB, planned); err != nil {
+			return err
+		}
 	}
 
-	if phaseErr != nil {
-		log.Fatalf("migration phase %q failed: %v", *phase, phaseErr)
+	log.Printf("=== Starting migration phase=%q dryRun=%v (%d phases) ===", *phase, *dryRun, len(planned))
+	if *dryRun && *dryRunMode == dryRunDiff {
+		return runDryRunDiff(ctx, oldDB, newDB, planned)
 	}
+	return runPhases(ctx, oldDB, newDB, planned, *dryRun)
+}
 
-	log.Printf("=== Migration phase=%q completed successfully in %s ===",
-		*phase, time.Since(start).Truncate(time.Millisecond))
+// runPhases runs the planned phases in order and stops at the first failure.
+func runPhases(ctx context.Context, oldDB, newDB *sql.DB, planned []phaseSpec, dryRun bool) error {
+	for i, p := range planned {
+		run.startPhase(p.name)
+		log.Printf("=== [%d/%d] phase %q ===", i+1, len(planned), p.name)
+		err := p.run(ctx, oldDB, newDB, dryRun)
+		run.endPhase(err)
+		if err != nil {
+			return fmt.Errorf("migration phase %q failed: %w", p.name, err)
+		}
+	}
+	return nil
 }
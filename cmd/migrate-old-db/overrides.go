@@ -0,0 +1,296 @@
+// cmd/migrate-old-db/overrides.go
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// The -overrides file pins old IDs that neither code nor name matching can
+// place, e.g. historical countries that only have an IMDb 4-letter code. It is
+// a small YAML subset: one block per id_map entity, one "old_id: target" line
+// per override. A numeric target is a new ID, anything else a new name.
+//
+//	country:
+//	  245: 191            # XYUG -> Serbia
+//	  312: "Soviet Union"
+//	genre:
+//	  27: Film-Noir
+//
+// Targets are looked up in the entity's new reference table (refMatchSpecs).
+// Titles and persons keep their old IDs in every phase, so they have no
+// overrides.
+
+// override is one old_id -> target line of the -overrides file.
+type override struct {
+	entity  string
+	oldID   int64
+	hasID   bool   // the target is newID (which may be 0), not newName
+	newID   int64  // set when hasID
+	newName string // set when !hasID
+	line    int
+}
+
+func (o override) target() string {
+	if !o.hasID {
+		return strconv.Quote(o.newName)
+	}
+	return strconv.FormatInt(o.newID, 10)
+}
+
+// overrideSet holds the loaded overrides and which ones a phase applied.
+type overrideSet struct {
+	path     string
+	byEntity map[string]map[int64]override
+
+	mu   sync.Mutex
+	used map[string]map[int64]int64 // entity -> old id -> resolved new id
+}
+
+// overrides is nil unless -overrides was given.
+var overrides *overrideSet
+
+// loadOverrides parses the -overrides file and rejects unknown entities and
+// duplicate old IDs.
+func loadOverrides(path string) (*overrideSet, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open overrides: %w", err)
+	}
+	defer f.Close()
+
+	known := make(map[string]bool)
+	for _, spec := range refMatchSpecs {
+		known[spec.entity] = true
+	}
+
+	set := &overrideSet{
+		path:     path,
+		byEntity: make(map[string]map[int64]override),
+		used:     make(map[string]map[int64]int64),
+	}
+	entity := ""
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		raw := sc.Text()
+		line := strings.TrimSpace(stripYAMLComment(raw))
+		if line == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: expected \"key: value\", got %q", path, n, line)
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+
+		// An unindented key opens an entity block.
+		if raw[0] != ' ' && raw[0] != '\t' {
+			if value != "" {
+				return nil, fmt.Errorf("%s:%d: entity %q must be followed by indented old_id: target lines", path, n, key)
+			}
+			if key == "title" || key == "person" {
+				return nil, fmt.Errorf("%s:%d: %s IDs are kept as is by every phase and can't be overridden", path, n, key)
+			}
+			if !known[key] {
+				return nil, fmt.Errorf("%s:%d: unknown entity %q", path, n, key)
+			}
+			entity = key
+			if set.byEntity[entity] == nil {
+				set.byEntity[entity] = make(map[int64]override)
+			}
+			continue
+		}
+
+		if entity == "" {
+			return nil, fmt.Errorf("%s:%d: override outside an entity block", path, n)
+		}
+		oldID, err := strconv.ParseInt(key, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: old id %q is not a number", path, n, key)
+		}
+		if prev, dup := set.byEntity[entity][oldID]; dup {
+			return nil, fmt.Errorf("%s:%d: %s old id %d already overridden on line %d", path, n, entity, oldID, prev.line)
+		}
+
+		o := override{entity: entity, oldID: oldID, line: n}
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			o.newName = unquoted
+		} else if id, err := strconv.ParseInt(value, 10, 64); err == nil {
+			o.newID, o.hasID = id, true
+		} else {
+			o.newName = value
+		}
+		if !o.hasID && o.newName == "" {
+			return nil, fmt.Errorf("%s:%d: %s old id %d has an empty target", path, n, entity, oldID)
+		}
+		set.byEntity[entity][oldID] = o
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read overrides: %w", err)
+	}
+	return set, nil
+}
+
+// stripYAMLComment drops a trailing # comment that is not inside quotes.
+func stripYAMLComment(s string) string {
+	inQuote := false
+	for i, r := range s {
+		switch r {
+		case '"':
+			inQuote = !inQuote
+		case '#':
+			if !inQuote && (i == 0 || s[i-1] == ' ' || s[i-1] == '\t') {
+				return s[:i]
+			}
+		}
+	}
+	return s
+}
+
+// resolve returns old id -> new id for every override of entity. Targets are
+// checked against the entity's refMatchSpecs newQuery: a name or ID that does
+// not exist there is an error, since the file is meant to be exact.
+func (s *overrideSet) resolve(ctx context.Context, newDB *sql.DB, entity string) (map[int64]int64, error) {
+	if s == nil || len(s.byEntity[entity]) == 0 {
+		return nil, nil
+	}
+
+	byName, ids, err := newRefs(ctx, newDB, entity)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[int64]int64, len(s.byEntity[entity]))
+	for oldID, o := range s.byEntity[entity] {
+		if o.hasID {
+			if !ids[o.newID] {
+				return nil, fmt.Errorf("%s:%d: %s override target id %d not found in the new DB", s.path, o.line, entity, o.newID)
+			}
+			out[oldID] = o.newID
+			continue
+		}
+		id, ok := byName[matchKey(o.newName)]
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: %s override target %q not found in the new DB", s.path, o.line, entity, o.newName)
+		}
+		out[oldID] = id
+	}
+	return out, nil
+}
+
+// check resolves every entity whose new reference table already has rows, so
+// a mistyped target stops the run before any phase writes. Entities the refs
+// phase has not filled yet are checked when a phase loads them.
+func (s *overrideSet) check(ctx context.Context, newDB *sql.DB) error {
+	if s == nil {
+		return nil
+	}
+	entities := make([]string, 0, len(s.byEntity))
+	for e := range s.byEntity {
+		entities = append(entities, e)
+	}
+	sort.Strings(entities)
+	for _, e := range entities {
+		_, ids, err := newRefs(ctx, newDB, e)
+		if err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			log.Printf("overrides: %s has no rows in the new DB yet; its targets are checked once refs has run", e)
+			continue
+		}
+		if _, err := s.resolve(ctx, newDB, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// apply overlays the overrides of entity onto m (old id -> new id) and records
+// which were used.
+func (s *overrideSet) apply(ctx context.Context, newDB *sql.DB, entity string, m map[int64]int64) error {
+	resolved, err := s.resolve(ctx, newDB, entity)
+	if err != nil {
+		return err
+	}
+	for oldID, newID := range resolved {
+		m[oldID] = newID
+		s.markUsed(entity, oldID, newID)
+	}
+	return nil
+}
+
+func (s *overrideSet) markUsed(entity string, oldID, newID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.used[entity] == nil {
+		s.used[entity] = make(map[int64]int64)
+	}
+	s.used[entity][oldID] = newID
+}
+
+// logReport lists the overrides the run applied and the ones it never needed.
+func (s *overrideSet) logReport() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entities := make([]string, 0, len(s.byEntity))
+	for e := range s.byEntity {
+		entities = append(entities, e)
+	}
+	sort.Strings(entities)
+	for _, e := range entities {
+		ids := make([]int64, 0, len(s.byEntity[e]))
+		for id := range s.byEntity[e] {
+			ids = append(ids, id)
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		for _, id := range ids {
+			o := s.byEntity[e][id]
+			if newID, ok := s.used[e][id]; ok {
+				log.Printf("overrides: used   %s old_id=%d -> %s (new id %d)", e, id, o.target(), newID)
+			} else {
+				log.Printf("overrides: unused %s old_id=%d -> %s (line %d; no phase in this run loaded %s)", e, id, o.target(), o.line, e)
+			}
+		}
+	}
+}
+
+// newRefs returns normalized name -> new id, and the set of new ids, for a
+// reference entity.
+func newRefs(ctx context.Context, newDB *sql.DB, entity string) (map[string]int64, map[int64]bool, error) {
+	for _, spec := range refMatchSpecs {
+		if spec.entity != entity {
+			continue
+		}
+		rows, err := newDB.QueryContext(ctx, spec.newQuery)
+		if err != nil {
+			return nil, nil, fmt.Errorf("select new %s refs: %w", entity, err)
+		}
+		defer rows.Close()
+		out := make(map[string]int64)
+		ids := make(map[int64]bool)
+		for rows.Next() {
+			var id int64
+			var name, code string
+			if err := rows.Scan(&id, &name, &code); err != nil {
+				return nil, nil, fmt.Errorf("scan new %s ref: %w", entity, err)
+			}
+			out[matchKey(name)] = id
+			ids[id] = true
+		}
+		return out, ids, rows.Err()
+	}
+	return nil, nil, fmt.Errorf("overrides: %s has no reference table to look names up in", entity)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStripYAMLComment(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"  245: 191", "  245: 191"},
+		{"  245: 191   # XYUG -> Serbia", "  245: 191   "},
+		{"# whole line", ""},
+		{`  312: "Soviet Union" # USSR`, `  312: "Soviet Union" `},
+		{`  27: "Rock #1"`, `  27: "Rock #1"`},
+		{`  27: "Rock #1" # quoted hash kept`, `  27: "Rock #1" `},
+		{"  28: C#", "  28: C#"},
+		{"country:\t# block", "country:\t"},
+	}
+	for _, tt := range tests {
+		if got := stripYAMLComment(tt.in); got != tt.want {
+			t.Errorf("stripYAMLComment(%q) = %q; want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func writeOverrides(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "overrides.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadOverrides(t *testing.T) {
+	path := writeOverrides(t, `
+# historical countries
+country:
+  245: 191            # XYUG -> Serbia
+  312: "Soviet Union"
+  400: 0
+genre:
+  27: Film-Noir
+  28: "Rock #1"  # quoted hash
+`)
+	set, err := loadOverrides(path)
+	if err != nil {
+		t.Fatalf("loadOverrides: %v", err)
+	}
+
+	type target struct {
+		hasID   bool
+		newID   int64
+		newName string
+	}
+	want := map[string]map[int64]target{
+		"country": {
+			245: {hasID: true, newID: 191},
+			312: {newName: "Soviet Union"},
+			400: {hasID: true, newID: 0},
+		},
+		"genre": {
+			27: {newName: "Film-Noir"},
+			28: {newName: "Rock #1"},
+		},
+	}
+	got := make(map[string]map[int64]target)
+	for entity, byID := range set.byEntity {
+		got[entity] = make(map[int64]target)
+		for id, o := range byID {
+			got[entity][id] = target{hasID: o.hasID, newID: o.newID, newName: o.newName}
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("loadOverrides = %+v; want %+v", got, want)
+	}
+}
+
+func TestLoadOverridesErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"title block", "title:\n  1: 2\n", "can't be overridden"},
+		{"person block", "person:\n  1: 2\n", "can't be overridden"},
+		{"unknown entity", "planet:\n  1: 2\n", `unknown entity "planet"`},
+		{"entity with value", "country: 1\n", "must be followed by indented"},
+		{"outside block", "  1: 2\n", "outside an entity block"},
+		{"old id not a number", "country:\n  XYUG: 191\n", `old id "XYUG" is not a number`},
+		{"duplicate old id", "country:\n  245: 191\n  245: 192\n", "already overridden on line 2"},
+		{"empty target", "country:\n  245: \"\"\n", "has an empty target"},
+		{"no colon", "country:\n  245 191\n", "expected \"key: value\""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadOverrides(writeOverrides(t, tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("loadOverrides error = %v; want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
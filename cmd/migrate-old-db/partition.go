@@ -0,0 +1,327 @@
+// cmd/migrate-old-db/partition.go
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// progressLogEvery is how often the combined progress of all workers is logged.
+const progressLogEvery = 10 * time.Second
+
+// titleRange is a half-open TitleID range [lo, hi).
+type titleRange struct {
+	lo, hi int64
+}
+
+func (r titleRange) String() string {
+	return fmt.Sprintf("[%d, %d)", r.lo, r.hi)
+}
+
+// titleIDRanges splits the TitleIDs of an old table into at most n ranges of
+// roughly equal row counts (percentiles, not an even split of the ID space).
+// An empty table yields no ranges.
+func titleIDRanges(ctx context.Context, oldDB *sql.DB, source string, n int) ([]titleRange, error) {
+	if n < 1 {
+		n = 1
+	}
+	fractions := make([]float64, 0, n-1)
+	for i := 1; i < n; i++ {
+		fractions = append(fractions, float64(i)/float64(n))
+	}
+
+	var (
+		minID, maxID sql.NullInt64
+		cuts         []int64
+	)
+	query := fmt.Sprintf(`
+		SELECT
+			MIN("TitleID"),
+			MAX("TitleID"),
+			percentile_disc($1::float8[]) WITHIN GROUP (ORDER BY "TitleID")
+		FROM %s
+	`, source)
+	if err := oldDB.QueryRowContext(ctx, query, pq.Array(fractions)).
+		Scan(&minID, &maxID, pq.Array(&cuts)); err != nil {
+		return nil, fmt.Errorf("compute TitleID ranges for %s: %w", source, err)
+	}
+	if !minID.Valid {
+		return nil, nil
+	}
+
+	var ranges []titleRange
+	lo := minID.Int64
+	for _, c := range cuts {
+		if c <= lo {
+			continue // skewed data: several percentiles on the same TitleID
+		}
+		ranges = append(ranges, titleRange{lo, c})
+		lo = c
+	}
+	ranges = append(ranges, titleRange{lo, maxID.Int64 + 1})
+	return ranges, nil
+}
+
+// runPartitioned runs fn for every range concurrently. The first error cancels
+// the context of the other workers; it is returned once all of them stopped.
+func runPartitioned(ctx context.Context, ranges []titleRange, fn func(ctx context.Context, worker int, r titleRange) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	for i, r := range ranges {
+		wg.Add(1)
+		go func(worker int, r titleRange) {
+			defer wg.Done()
+			if err := fn(ctx, worker, r); err != nil {
+				mu.Lock()
+				// Prefer the error that caused the cancellation over the
+				// context errors it triggers in the other workers.
+				if firstErr == nil || (errors.Is(firstErr, context.Canceled) && !errors.Is(err, context.Canceled)) {
+					firstErr = fmt.Errorf("worker %d %s: %w", worker, r, err)
+				}
+				mu.Unlock()
+				cancel()
+			}
+		}(i, r)
+	}
+	wg.Wait()
+	return firstErr
+}
+
+// jobProgress aggregates counts across workers. A nil *jobProgress is valid
+// and counts nothing.
+type jobProgress struct {
+	name      string
+	total     int64
+	start     time.Time
+	processed atomic.Int64
+	skipped   atomic.Int64
+	rangesOK  atomic.Int64
+	ranges    int
+}
+
+func newJobProgress(name string, total int64) *jobProgress {
+	return &jobProgress{name: name, total: total, start: time.Now()}
+}
+
+func (p *jobProgress) add(processed, skipped int64) {
+	if p == nil {
+		return
+	}
+	if processed != 0 {
+		p.processed.Add(processed)
+	}
+	if skipped != 0 {
+		p.skipped.Add(skipped)
+	}
+}
+
+func (p *jobProgress) log(prefix string) {
+	processed, skipped := p.processed.Load(), p.skipped.Load()
+	pct := 0.0
+	if p.total > 0 {
+		pct = float64(processed+skipped) * 100.0 / float64(p.total)
+	}
+	logProgress(p.name, processed, skipped, p.start,
+		"%s %s: %d rows processed, %d skipped, %d/%d (%.1f%%) read, %d/%d ranges done, %.0f rows/s",
+		prefix, p.name, processed, skipped, processed+skipped, p.total, pct,
+		p.rangesOK.Load(), p.ranges, rowsPerSecond(processed, time.Since(p.start)))
+}
+
+// logDone logs the final progress line and records the counts as a finished
+// step on migration_run.
+func (p *jobProgress) logDone() {
+	run.addStep(p.name, p.processed.Load(), p.skipped.Load(), time.Since(p.start))
+	p.log("done")
+}
+
+// report logs the combined progress every progressLogEvery until stop is called.
+func (p *jobProgress) report() (stop func()) {
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		t := time.NewTicker(progressLogEvery)
+		defer t.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-t.C:
+				p.log("progress")
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
+
+// runJunctionJob runs a junction loadJob of phase over -workers TitleID
+// ranges, each worker on its own connection to the new DB. The write path
+// follows -mode.
+func runJunctionJob(ctx context.Context, oldDB, newDB *sql.DB, phase string, job loadJob, total int64) error {
+	ranges, err := titleIDRanges(ctx, oldDB, job.rangeSource, *workers)
+	if err != nil {
+		return err
+	}
+	if len(ranges) == 0 {
+		logStepDone(job.target, 0, 0, time.Now(), "--- Done %s: source %s is empty ---", job.target, job.rangeSource)
+		return nil
+	}
+	log.Printf("%s: %d rows in %s, %d worker(s) over TitleID ranges %v [mode=%s]",
+		job.target, total, job.rangeSource, len(ranges), ranges, *mode)
+
+	var titleIDs, personIDs map[int64]struct{}
+	if *mode == modeRow {
+		if err := resetRejects(ctx, newDB, phase, job.target); err != nil {
+			return err
+		}
+		if job.requireTitle {
+			if titleIDs, err = loadNewTitleIDSet(ctx, newDB); err != nil {
+				return err
+			}
+		}
+		if job.requirePerson {
+			if personIDs, err = loadNewPersonIDSet(ctx, newDB); err != nil {
+				return err
+			}
+		}
+	}
+
+	progress := newJobProgress(job.target, total)
+	progress.ranges = len(ranges)
+	stop := progress.report()
+
+	err = runPartitioned(ctx, ranges, func(ctx context.Context, worker int, r titleRange) error {
+		wj := job
+		wj.srcArgs = []interface{}{r.lo, r.hi}
+		if len(ranges) > 1 {
+			wj.stagingSuffix = fmt.Sprintf("_w%d", worker)
+		}
+
+		var err error
+		if *mode == modeCopy {
+			err = runCopyJob(ctx, oldDB, newDB, wj, progress)
+		} else {
+			err = runRowJob(ctx, oldDB, newDB, phase, wj, titleIDs, personIDs, progress)
+		}
+		if err == nil {
+			progress.rangesOK.Add(1)
+		}
+		return err
+	})
+	stop()
+	if err != nil {
+		progress.log("stopped")
+		return err
+	}
+
+	logStepDone(job.target, progress.processed.Load(), progress.skipped.Load(), progress.start,
+		"--- Done %s: %d rows processed, %d skipped in %s (%.0f rows/s) ---",
+		job.target, progress.processed.Load(), progress.skipped.Load(),
+		time.Since(progress.start), rowsPerSecond(progress.processed.Load(), time.Since(progress.start)))
+	return nil
+}
+
+// runRowJob upserts one range in -batch-size transactions. Rows with no ID
+// mapping, or whose title / person is not in the new DB, are skipped; rows
+// the new DB refuses go to migration_reject.
+func runRowJob(
+	ctx context.Context,
+	oldDB, newDB *sql.DB,
+	phase string,
+	job loadJob,
+	titleIDs, personIDs map[int64]struct{},
+	progress *jobProgress,
+) error {
+	w := openBatchWriter(newDB, phase, job.target, []string{job.insertSQL()}, job.columns...)
+	err := streamJobRows(ctx, oldDB, job, titleIDs, personIDs, progress, func(values []interface{}) error {
+		key, _ := values[0].(int64)
+		return w.add(ctx, key, values...)
+	})
+	if err != nil {
+		return err
+	}
+	return w.close(ctx)
+}
+
+// insertJobRows streams job's source rows into tx. The caller commits.
+func insertJobRows(
+	ctx context.Context,
+	oldDB *sql.DB,
+	tx *sql.Tx,
+	job loadJob,
+	titleIDs, personIDs map[int64]struct{},
+	progress *jobProgress,
+) error {
+	stmt, err := tx.PrepareContext(ctx, job.insertSQL())
+	if err != nil {
+		return fmt.Errorf("prepare insert %s: %w", job.target, err)
+	}
+	defer stmt.Close()
+
+	return streamJobRows(ctx, oldDB, job, titleIDs, personIDs, progress, func(values []interface{}) error {
+		if _, err := stmt.ExecContext(ctx, values...); err != nil {
+			return fmt.Errorf("insert %s %v: %w", job.target, values, err)
+		}
+		return nil
+	})
+}
+
+// streamJobRows reads job's source rows and passes the ones to keep to write.
+func streamJobRows(
+	ctx context.Context,
+	oldDB *sql.DB,
+	job loadJob,
+	titleIDs, personIDs map[int64]struct{},
+	progress *jobProgress,
+	write func(values []interface{}) error,
+) error {
+	rows, err := oldDB.QueryContext(ctx, job.srcQuery, job.srcArgs...)
+	if err != nil {
+		return fmt.Errorf("query source for %s: %w", job.target, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		values, keep, err := job.scan(rows)
+		if err != nil {
+			return err
+		}
+		if keep && job.requireTitle {
+			_, keep = titleIDs[values[0].(int64)]
+		}
+		if keep && job.requirePerson {
+			_, keep = personIDs[values[1].(int64)]
+		}
+		if !keep {
+			progress.add(0, 1)
+			continue
+		}
+
+		if err := write(values); err != nil {
+			return err
+		}
+		progress.add(1, 0)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate source for %s: %w", job.target, err)
+	}
+	return nil
+}
@@ -0,0 +1,317 @@
+// cmd/migrate-old-db/phase_companies.go
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MigrateCompaniesPhase runs the "companies" phase:
+//
+//	Tables."CompanyTable" + public."CompanyTable" -> company
+//	Lines."CompanyTitleLine"                      -> title_company
+func MigrateCompaniesPhase(ctx context.Context, oldDB, newDB *sql.DB, dryRun bool) error {
+	start := time.Now()
+	log.Printf("=== Starting migration phase=\"companies\" dryRun=%v ===", dryRun)
+
+	companyIDMap, err := migrateCompanies(ctx, oldDB, newDB, dryRun)
+	if err != nil {
+		return fmt.Errorf("migrateCompanies: %w", err)
+	}
+
+	if err := migrateTitleCompany(ctx, oldDB, newDB, companyIDMap, dryRun); err != nil {
+		return fmt.Errorf("migrateTitleCompany: %w", err)
+	}
+
+	log.Printf("=== Migration phase=\"companies\" completed successfully in %s ===", time.Since(start))
+	return nil
+}
+
+// ======================
+//   COMPANY MIGRATION
+// ======================
+//
+// OLD: Tables."CompanyTable"  ("CompanyID" integer PK, "CompanyName" varchar NOT NULL)
+//      public."CompanyTable"  ("CompanyID" integer NULL, "CompanyName" varchar NULL)  -- stray copy
+//
+// NEW: company (id INTEGER PK, name TEXT NOT NULL UNIQUE)
+//
+// Merge rules:
+//   - Tables."CompanyTable" is authoritative (CompanyTitleLine references it),
+//     its IDs are kept as-is.
+//   - Names are compared case-insensitively with whitespace collapsed. When two
+//     old rows share a name, the lowest ID wins and the others are folded into it.
+//   - public rows whose ID already exists in Tables with a different name are an
+//     ID conflict: the Tables name keeps the ID, the public name gets a fresh ID.
+//   - public rows with a NULL ID get a fresh ID above every old ID.
+
+type companyRow struct {
+	id   sql.NullInt64
+	name string
+}
+
+// migrateCompanies merges both old company tables into company and returns
+// old Tables."CompanyTable".CompanyID -> new company.id.
+func migrateCompanies(ctx context.Context, oldDB, newDB *sql.DB, dryRun bool) (map[int64]int64, error) {
+	log.Println("--- Migrating company (Tables.CompanyTable + public.CompanyTable → company) ---")
+
+	primary, err := readCompanyRows(ctx, oldDB, `
+		SELECT "CompanyID", "CompanyName"
+		FROM "Tables"."CompanyTable"
+		ORDER BY "CompanyID"
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("read Tables.CompanyTable: %w", err)
+	}
+	stray, err := readCompanyRows(ctx, oldDB, `
+		SELECT "CompanyID", COALESCE("CompanyName", '')
+		FROM public."CompanyTable"
+		ORDER BY "CompanyID" NULLS LAST
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("read public.CompanyTable: %w", err)
+	}
+	log.Printf("migrateCompanies: read %d rows from Tables.\"CompanyTable\", %d rows from public.\"CompanyTable\"",
+		len(primary), len(stray))
+
+	var (
+		names        = make(map[int64]string) // new id -> name
+		idByKey      = make(map[string]int64) // normalized name -> new id
+		keyByOldID   = make(map[int64]string) // Tables id -> normalized name
+		idMap        = make(map[int64]int64)  // Tables id -> new id
+		maxID        int64
+		emptyNames   int64
+		nameMerges   int64
+		idConflicts  int64
+		strayAdded   int64
+		strayMatched int64
+	)
+
+	for _, r := range primary {
+		if r.id.Int64 > maxID {
+			maxID = r.id.Int64
+		}
+	}
+	for _, r := range stray {
+		if r.id.Valid && r.id.Int64 > maxID {
+			maxID = r.id.Int64
+		}
+	}
+	nextID := maxID + 1
+
+	// 1) Authoritative Tables."CompanyTable"
+	for _, r := range primary {
+		key := companyNameKey(r.name)
+		if key == "" {
+			emptyNames++
+			continue
+		}
+		keyByOldID[r.id.Int64] = key
+
+		if existing, ok := idByKey[key]; ok {
+			idMap[r.id.Int64] = existing
+			nameMerges++
+			if nameMerges <= 20 {
+				log.Printf("WARN: migrateCompanies: CompanyID=%d name=%q duplicates company id=%d name=%q; merged",
+					r.id.Int64, r.name, existing, names[existing])
+			}
+			continue
+		}
+
+		names[r.id.Int64] = strings.TrimSpace(r.name)
+		idByKey[key] = r.id.Int64
+		idMap[r.id.Int64] = r.id.Int64
+	}
+
+	// 2) Stray public."CompanyTable"
+	for _, r := range stray {
+		key := companyNameKey(r.name)
+		if key == "" {
+			emptyNames++
+			continue
+		}
+
+		if r.id.Valid {
+			if primaryKey, ok := keyByOldID[r.id.Int64]; ok && primaryKey != key {
+				idConflicts++
+				if idConflicts <= 20 {
+					log.Printf("WARN: migrateCompanies: CompanyID=%d is %q in Tables but %q in public; keeping Tables name",
+						r.id.Int64, names[idMap[r.id.Int64]], r.name)
+				}
+			}
+		}
+
+		if _, ok := idByKey[key]; ok {
+			strayMatched++
+			continue
+		}
+
+		id := nextID
+		if r.id.Valid {
+			if _, taken := names[r.id.Int64]; !taken {
+				if _, primaryID := keyByOldID[r.id.Int64]; !primaryID {
+					id = r.id.Int64
+				}
+			}
+		}
+		if id == nextID {
+			nextID++
+		}
+
+		names[id] = strings.TrimSpace(r.name)
+		idByKey[key] = id
+		strayAdded++
+	}
+
+	log.Printf("migrateCompanies: %d companies after merge (%d name merges, %d ID conflicts, %d public-only rows added, %d public rows matched existing, %d empty names dropped)",
+		len(names), nameMerges, idConflicts, strayAdded, strayMatched, emptyNames)
+
+	if dryRun {
+		log.Printf("migrateCompanies [DRY-RUN]: would insert/update %d company rows", len(names))
+		return idMap, nil
+	}
+
+	ids := make([]int64, 0, len(names))
+	for id := range names {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	start := time.Now()
+	w, err := newBatchWriter(ctx, newDB, "companies", "company", []string{`
+		INSERT INTO company (id, name)
+		VALUES ($1, $2)
+		ON CONFLICT (id) DO UPDATE
+		SET name = EXCLUDED.name
+	`}, "id", "name")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range ids {
+		if err := w.add(ctx, id, id, names[id]); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := w.close(ctx); err != nil {
+		return nil, err
+	}
+
+	logStepDone("company", int64(len(ids)), 0, start, "--- Done company: %d rows inserted/updated ---", len(ids))
+	return idMap, nil
+}
+
+func readCompanyRows(ctx context.Context, oldDB *sql.DB, query string) ([]companyRow, error) {
+	rows, err := oldDB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []companyRow
+	for rows.Next() {
+		var r companyRow
+		if err := rows.Scan(&r.id, &r.name); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// companyNameKey normalizes a company name for duplicate detection.
+func companyNameKey(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// CompanyTitleLine -> title_company
+func migrateTitleCompany(
+	ctx context.Context,
+	oldDB, newDB *sql.DB,
+	companyIDMap map[int64]int64,
+	dryRun bool,
+) error {
+	var total int64
+	if err := oldDB.QueryRowContext(ctx, `SELECT COUNT(*) FROM "Lines"."CompanyTitleLine"`).Scan(&total); err != nil {
+		return fmt.Errorf("count CompanyTitleLine: %w", err)
+	}
+	log.Printf("migrateTitleCompany: %d rows in Lines.\"CompanyTitleLine\"", total)
+
+	if dryRun {
+		log.Printf("migrateTitleCompany [DRY-RUN]: would process %d rows", total)
+		return nil
+	}
+
+	titleIDs, err := loadNewTitleIDSet(ctx, newDB)
+	if err != nil {
+		return err
+	}
+
+	rows, err := oldDB.QueryContext(ctx, `
+        SELECT "TitleID", "CompanyID"
+        FROM "Lines"."CompanyTitleLine"
+        ORDER BY "TitleID"
+    `)
+	if err != nil {
+		return fmt.Errorf("query CompanyTitleLine: %w", err)
+	}
+	defer rows.Close()
+
+	w, err := newBatchWriter(ctx, newDB, "companies", "title_company", []string{`
+        INSERT INTO title_company (title_id, company_id)
+        VALUES ($1, $2)
+        ON CONFLICT (title_id, company_id) DO NOTHING
+    `}, "title_id", "company_id")
+	if err != nil {
+		return err
+	}
+
+	var processed int64
+	var skippedTitle, skippedCompany int64
+	start := time.Now()
+
+	for rows.Next() {
+		var titleID, oldCompanyID int64
+		if err := rows.Scan(&titleID, &oldCompanyID); err != nil {
+			return fmt.Errorf("scan CompanyTitleLine: %w", err)
+		}
+
+		if _, ok := titleIDs[titleID]; !ok {
+			skippedTitle++
+			continue
+		}
+		newCompanyID, ok := companyIDMap[oldCompanyID]
+		if !ok {
+			skippedCompany++
+			continue
+		}
+
+		if err := w.add(ctx, titleID, titleID, newCompanyID); err != nil {
+			return err
+		}
+
+		processed++
+		if processed%junctionProgressEvery == 0 && total > 0 {
+			pct := float64(processed) * 100.0 / float64(total)
+			logProgress("title_company", processed, skippedTitle+skippedCompany, start,
+				"migrateTitleCompany: inserted %d/%d rows (%.1f%%)", processed, total, pct)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate CompanyTitleLine: %w", err)
+	}
+
+	if err := w.close(ctx); err != nil {
+		return err
+	}
+	logStepDone("title_company", processed, skippedTitle+skippedCompany, start,
+		"--- Done title_company: %d rows processed, %d skipped (no title=%d, no company mapping=%d) ---",
+		processed, skippedTitle+skippedCompany, skippedTitle, skippedCompany)
+	return nil
+}
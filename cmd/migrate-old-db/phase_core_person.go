@@ -20,6 +20,12 @@ func MigrateCorePersonsPhase(ctx context.Context, oldDB, newDB *sql.DB, dryRun b
 		return fmt.Errorf("migratePersons: %w", err)
 	}
 
+	if !dryRun {
+		if err := saveIdentityIDMap(ctx, newDB, "person", "person"); err != nil {
+			return err
+		}
+	}
+
 	log.Printf("=== Migration phase=\"core-person\" completed successfully in %s ===", time.Since(start))
 	return nil
 }
@@ -34,16 +40,23 @@ func MigrateCorePersonsPhase(ctx context.Context, oldDB, newDB *sql.DB, dryRun b
 //   "IsDirector"    boolean
 //   "IsWriter"      boolean
 //   "IsCharacter"   boolean
+//   "CastImageURL"  varchar
+//   "CastDescription" varchar
 //
 // NEW: public.person
 //   id                  BIGINT PK
 //   imdb_id             TEXT (unused for now)
 //   name                TEXT NOT NULL
 //   primary_profession  TEXT
+//   image_url           TEXT  <-- CastImageURL (normalized)
+//   bio                 TEXT  <-- CastDescription (normalized)
 //   created_at          TIMESTAMPTZ NOT NULL
 //   updated_at          TIMESTAMPTZ NOT NULL
 //
 // We keep IDs identical so junction tables can refer to them.
+//
+// Progress is checkpointed as ("core-persons", "person", last CastID); with
+// -resume a rerun continues after that CastID.
 
 func migratePersons(ctx context.Context, oldDB, newDB *sql.DB, dryRun bool) error {
 	log.Println("--- Migrating person (CastTable → person) ---")
@@ -60,88 +73,185 @@ func migratePersons(ctx context.Context, oldDB, newDB *sql.DB, dryRun bool) erro
 		return nil
 	}
 
-	// 2) Prepare insert/upsert statement in new DB
-	stmt, err := newDB.PrepareContext(ctx, `
+	// Persons merge-persons merged away stay merged.
+	merges, err := loadPersonMerges(ctx, newDB)
+	if err != nil {
+		return err
+	}
+
+	if *mode == modeCopy {
+		if *resume {
+			log.Println("migratePersons: -resume is ignored with -mode=copy (the load is one transaction)")
+		}
+		return copyPersons(ctx, oldDB, newDB, merges)
+	}
+
+	cp, err := startCheckpoint(ctx, newDB, "core-persons", "person")
+	if err != nil {
+		return err
+	}
+	if cp.completed {
+		log.Println("migratePersons: person step already completed; skipping (run without -resume to redo it)")
+		return nil
+	}
+
+	// 2) Batch writer for the insert/upsert in new DB
+	w, err := newBatchWriter(ctx, newDB, "core-persons", "person", []string{`
 		INSERT INTO person (
 			id,
 			name,
 			primary_profession,
+			image_url,
+			bio,
 			created_at,
 			updated_at
 		) VALUES (
-			$1, $2, $3, now(), now()
+			$1, $2, $3, $4, $5, now(), now()
 		)
 		ON CONFLICT (id) DO UPDATE
 		SET
 			name               = EXCLUDED.name,
 			primary_profession = EXCLUDED.primary_profession,
+			image_url          = EXCLUDED.image_url,
+			bio                = EXCLUDED.bio,
 			updated_at         = now()
-	`)
+	`}, personInsertColumns...)
 	if err != nil {
-		return fmt.Errorf("prepare insert person: %w", err)
+		return err
 	}
-	defer stmt.Close()
+	w.onCommit = cp.saveEvery(cp.rowsDone)
 
 	// 3) Stream rows from old DB
-	rows, err := oldDB.QueryContext(ctx, `
-		SELECT
-			"CastID",
-			"CastName",
-			COALESCE("IsDirector", false)  AS is_director,
-			COALESCE("IsWriter", false)    AS is_writer,
-			COALESCE("IsCharacter", false) AS is_character
-		FROM "Tables"."CastTable"
-		ORDER BY "CastID"
-	`)
+	rows, err := oldDB.QueryContext(ctx, personSelectSQL, cp.lastKey)
 	if err != nil {
 		return fmt.Errorf("select CastTable: %w", err)
 	}
 	defer rows.Close()
 
-	var processed int64
-	lastLog := time.Now()
+	// Every checkpoint write stores resumedFrom+w.done(): rows sent to the
+	// writer. Merged-away persons are not in it; they are only counted.
+	processed := cp.rowsDone
+	resumedFrom := cp.rowsDone
+	lastKey := cp.lastKey
+	var merged int64
+	start := time.Now()
+	lastLog := start
 
 	for rows.Next() {
-		var (
-			id          int64
-			name        string
-			isDirector  bool
-			isWriter    bool
-			isCharacter bool
-		)
-
-		if err := rows.Scan(&id, &name, &isDirector, &isWriter, &isCharacter); err != nil {
-			return fmt.Errorf("scan CastTable row: %w", err)
-		}
-
-		// Build primary_profession from flags
-		var profs []string
-		if isDirector {
-			profs = append(profs, "director")
-		}
-		if isWriter {
-			profs = append(profs, "writer")
+		id, values, err := scanPersonRow(rows)
+		if err != nil {
+			return err
 		}
-		if isCharacter {
-			profs = append(profs, "actor")
+		if _, ok := merges[id]; ok {
+			merged++
+			continue
 		}
-		primaryProfession := strings.Join(profs, ",")
 
-		if _, err := stmt.ExecContext(ctx, id, name, primaryProfession); err != nil {
-			return fmt.Errorf("insert person id=%d: %w", id, err)
+		if err := w.add(ctx, id, values...); err != nil {
+			cp.saveOnError(ctx, w.lastKey, resumedFrom+w.done())
+			return fmt.Errorf("insert person: %w", err)
 		}
 
 		processed++
+		lastKey = id
 		if processed%50000 == 0 || time.Since(lastLog) > 10*time.Second {
 			pct := float64(processed) * 100.0 / float64(total)
-			log.Printf("migratePersons: inserted/updated %d/%d persons (%.1f%%)", processed, total, pct)
+			logProgress("person", processed-resumedFrom, 0, start,
+				"migratePersons: inserted/updated %d/%d persons (%.1f%%)", processed, total, pct)
 			lastLog = time.Now()
 		}
 	}
 	if err := rows.Err(); err != nil {
+		if err := w.flush(ctx); err == nil {
+			cp.saveOnError(ctx, w.lastKey, resumedFrom+w.done())
+		}
 		return fmt.Errorf("iterate CastTable: %w", err)
 	}
+	if err := w.close(ctx); err != nil {
+		cp.saveOnError(ctx, w.lastKey, resumedFrom+w.done())
+		return fmt.Errorf("insert person: %w", err)
+	}
+
+	if err := cp.complete(ctx, lastKey, resumedFrom+w.done()); err != nil {
+		return err
+	}
 
-	log.Printf("--- Done person: %d rows processed ---", processed)
+	logStepDone("person", processed-resumedFrom, merged, start, "--- Done person: %d rows processed (%d merged-away persons skipped) in %s (%.0f rows/s) ---",
+		processed, merged, time.Since(start), rowsPerSecond(processed-resumedFrom, time.Since(start)))
 	return nil
 }
+
+// personInsertColumns are the person columns scanPersonRow produces values for.
+var personInsertColumns = []string{"id", "name", "primary_profession", "image_url", "bio"}
+
+// personSelectSQL streams CastTable rows after key $1, in the column order
+// scanPersonRow expects.
+const personSelectSQL = `
+	SELECT
+		"CastID",
+		"CastName",
+		COALESCE("IsDirector", false)  AS is_director,
+		COALESCE("IsWriter", false)    AS is_writer,
+		COALESCE("IsCharacter", false) AS is_character,
+		"CastImageURL",
+		"CastDescription"
+	FROM "Tables"."CastTable"
+	WHERE "CastID" > $1
+	ORDER BY "CastID"
+`
+
+// scanPersonRow reads one personSelectSQL row and returns the values for
+// (id, name, primary_profession, image_url, bio).
+func scanPersonRow(rows *sql.Rows) (int64, []interface{}, error) {
+	var (
+		id          int64
+		name        string
+		isDirector  bool
+		isWriter    bool
+		isCharacter bool
+		imageURL    sql.NullString
+		description sql.NullString
+	)
+
+	if err := rows.Scan(&id, &name, &isDirector, &isWriter, &isCharacter, &imageURL, &description); err != nil {
+		return 0, nil, fmt.Errorf("scan CastTable row: %w", err)
+	}
+
+	// Build primary_profession from flags
+	var profs []string
+	if isDirector {
+		profs = append(profs, "director")
+	}
+	if isWriter {
+		profs = append(profs, "writer")
+	}
+	if isCharacter {
+		profs = append(profs, "actor")
+	}
+	primaryProfession := strings.Join(profs, ",")
+
+	return id, []interface{}{
+		id,
+		name,
+		primaryProfession,
+		normalizeLineOrNil(imageURL),
+		normalizeTextOrNil(description),
+	}, nil
+}
+
+// copyPersons is the -mode=copy path for migratePersons.
+func copyPersons(ctx context.Context, oldDB, newDB *sql.DB, merges map[int64]int64) error {
+	return runCopyJob(ctx, oldDB, newDB, loadJob{
+		target:     "person",
+		columns:    personInsertColumns,
+		keyColumns: []string{"id"},
+		extraSet:   "updated_at = now()",
+		srcQuery:   personSelectSQL,
+		srcArgs:    []interface{}{0},
+		scan: func(rows *sql.Rows) ([]interface{}, bool, error) {
+			id, values, err := scanPersonRow(rows)
+			_, merged := merges[id]
+			return values, err == nil && !merged, err
+		},
+	}, nil)
+}
@@ -24,12 +24,90 @@ func MigrateCoreTitlesPhase(ctx context.Context, oldDB, newDB *sql.DB, dryRun bo
 		if err := backfillTitleParents(ctx, oldDB, newDB); err != nil {
 			return fmt.Errorf("backfillTitleParents: %w", err)
 		}
+		if err := saveIdentityIDMap(ctx, newDB, "title", "title"); err != nil {
+			return err
+		}
 	}
 
 	log.Printf("=== Migration phase=%q completed successfully in %s ===", "core-title", time.Since(start))
 	return nil
 }
 
+// titleSelectSQL streams TitleTable rows after key $1, in the column order
+// scanTitleRow expects.
+const titleSelectSQL = `
+SELECT
+	"TitleID",
+	"TitleType",
+	"TitleName",
+	"OriginalTitle",
+	"TitleYear",
+	"TitleLength",
+	"TitleCountry",
+	"PosterURL",
+	"MetacriticRating",
+	"Revenue",
+	"IMDbRating",
+	"IMDbVotes",
+	"Popularity",
+	"ParentID",
+	"EpisodeSeason",
+	"EpisodeNumber",
+	"TotalSeasons",
+	"TotalEpisodes",
+	"DateReleased",
+	"DateAdded",
+	"DateUpdated",
+	"Available",
+	"Viewed",
+	"Played",
+	"Liked",
+	"UnLiked",
+	"FolderName",
+	"FolderPath",
+	"TitleSummary",
+	"TitleStoryLine",
+	"TitleCategory"
+FROM "Tables"."TitleTable"
+WHERE "TitleID" > $1
+ORDER BY "TitleID"
+`
+
+// titleInsertColumns are the title columns scanTitleRow produces values for.
+var titleInsertColumns = []string{
+	"id",
+	"title_type_id",
+	"primary_title",
+	"original_title",
+	"start_year",
+	"runtime_minutes",
+	"primary_country_id",
+	"poster_url",
+	"metacritic_rating",
+	"revenue",
+	"imdb_rating",
+	"imdb_votes",
+	"popularity",
+	"parent_title_id",
+	"season_number",
+	"episode_number",
+	"total_seasons",
+	"total_episodes",
+	"date_released",
+	"date_added",
+	"date_updated",
+	"is_available",
+	"viewed_count",
+	"played_count",
+	"liked_count",
+	"disliked_count",
+	"folder_name",
+	"folder_path",
+	"plot",
+	"storyline",
+	"category_id",
+}
+
 func migrateTitles(ctx context.Context, oldDB, newDB *sql.DB, dryRun bool) error {
 	log.Println("--- Migrating title (TitleTable → title) ---")
 
@@ -45,6 +123,27 @@ func migrateTitles(ctx context.Context, oldDB, newDB *sql.DB, dryRun bool) error
 		return nil
 	}
 
+	refs, err := loadTitleRefMaps(ctx, newDB)
+	if err != nil {
+		return err
+	}
+
+	if *mode == modeCopy {
+		if *resume {
+			log.Println("migrateTitles: -resume is ignored with -mode=copy (the load is one transaction)")
+		}
+		return copyTitles(ctx, oldDB, newDB, refs)
+	}
+
+	cp, err := startCheckpoint(ctx, newDB, "core-title", "title")
+	if err != nil {
+		return err
+	}
+	if cp.completed {
+		log.Println("migrateTitles: title step already completed; skipping (run without -resume to redo it)")
+		return nil
+	}
+
 	// 2) Prepare INSERT for new title table
 	//
 	// Columns we populate:
@@ -75,9 +174,12 @@ func migrateTitles(ctx context.Context, oldDB, newDB *sql.DB, dryRun bool) error
 	//   liked_count,
 	//   disliked_count,
 	//   folder_name,
-	//   folder_path
+	//   folder_path,
+	//   plot,             <-- TitleSummary (normalized)
+	//   storyline,        <-- TitleStoryLine (normalized)
+	//   category_id       <-- TitleCategory (through the category id_map)
 	//
-	// 28 columns → 28 VALUES placeholders.
+	// 31 columns → 31 VALUES placeholders.
 	const insertSQL = `
 INSERT INTO title (
 	id,
@@ -107,12 +209,16 @@ INSERT INTO title (
 	liked_count,
 	disliked_count,
 	folder_name,
-	folder_path
+	folder_path,
+	plot,
+	storyline,
+	category_id
 ) VALUES (
 	$1,  $2,  $3,  $4,  $5,  $6,  $7,
 	$8,  $9,  $10, $11, $12, $13, $14,
 	$15, $16, $17, $18, $19, $20, $21,
-	$22, $23, $24, $25, $26, $27, $28
+	$22, $23, $24, $25, $26, $27, $28,
+	$29, $30, $31
 )
 ON CONFLICT (id) DO UPDATE SET
 	title_type_id      = EXCLUDED.title_type_id,
@@ -141,51 +247,21 @@ ON CONFLICT (id) DO UPDATE SET
 	liked_count        = EXCLUDED.liked_count,
 	disliked_count     = EXCLUDED.disliked_count,
 	folder_name        = EXCLUDED.folder_name,
-	folder_path        = EXCLUDED.folder_path;
+	folder_path        = EXCLUDED.folder_path,
+	plot               = EXCLUDED.plot,
+	storyline          = EXCLUDED.storyline,
+	category_id        = EXCLUDED.category_id;
 `
 
-	stmt, err := newDB.PrepareContext(ctx, insertSQL)
+	w, err := newBatchWriter(ctx, newDB, "core-title", "title", []string{insertSQL}, titleInsertColumns...)
 	if err != nil {
-		return fmt.Errorf("prepare insert title: %w", err)
+		return err
 	}
-	defer stmt.Close()
+	w.onCommit = cp.saveEvery(cp.rowsDone)
 
 	// 3) Stream rows from old TitleTable
-	const selectSQL = `
-SELECT
-	"TitleID",
-	"TitleType",
-	"TitleName",
-	"OriginalTitle",
-	"TitleYear",
-	"TitleLength",
-	"TitleCountry",
-	"PosterURL",
-	"MetacriticRating",
-	"Revenue",
-	"IMDbRating",
-	"IMDbVotes",
-	"Popularity",
-	"ParentID",
-	"EpisodeSeason",
-	"EpisodeNumber",
-	"TotalSeasons",
-	"TotalEpisodes",
-	"DateReleased",
-	"DateAdded",
-	"DateUpdated",
-	"Available",
-	"Viewed",
-	"Played",
-	"Liked",
-	"UnLiked",
-	"FolderName",
-	"FolderPath"
-FROM "Tables"."TitleTable"
-ORDER BY "TitleID"
-`
 
-	rows, err := oldDB.QueryContext(ctx, selectSQL)
+	rows, err := oldDB.QueryContext(ctx, titleSelectSQL, cp.lastKey)
 	if err != nil {
 		return fmt.Errorf("query TitleTable: %w", err)
 	}
@@ -193,181 +269,269 @@ ORDER BY "TitleID"
 
 	start := time.Now()
 	var (
-		processed int64
-		inserted  int64
+		processed   int64
+		inserted    = cp.rowsDone // counts rows from earlier runs when resuming
+		resumedFrom = cp.rowsDone
+		lastKey     = cp.lastKey
 	)
 
 	for rows.Next() {
-		var (
-			titleID       int64
-			titleType     sql.NullInt64
-			titleName     string
-			originalTitle sql.NullString
-			titleYear     sql.NullInt64
-			titleLength   sql.NullInt64
-			titleCountry  sql.NullInt64
-			posterURL     sql.NullString
-			metacritic    sql.NullInt64
-			revenue       sql.NullInt64
-			imdbRating    sql.NullFloat64
-			imdbVotes     sql.NullInt64
-			popularity    sql.NullInt64
-			parentID      sql.NullInt64
-			episodeSeason sql.NullString
-			episodeNumber sql.NullInt64
-			totalSeasons  sql.NullInt64
-			totalEpisodes sql.NullInt64
-			dateReleased  sql.NullTime
-			dateAdded     time.Time
-			dateUpdated   sql.NullTime
-			available     sql.NullBool
-			viewed        sql.NullInt64
-			played        sql.NullInt64
-			liked         sql.NullInt64
-			unliked       sql.NullInt64
-			folderName    sql.NullString
-			folderPath    sql.NullString
-		)
-
-		if err := rows.Scan(
-			&titleID,
-			&titleType,
-			&titleName,
-			&originalTitle,
-			&titleYear,
-			&titleLength,
-			&titleCountry,
-			&posterURL,
-			&metacritic,
-			&revenue,
-			&imdbRating,
-			&imdbVotes,
-			&popularity,
-			&parentID,
-			&episodeSeason,
-			&episodeNumber,
-			&totalSeasons,
-			&totalEpisodes,
-			&dateReleased,
-			&dateAdded,
-			&dateUpdated,
-			&available,
-			&viewed,
-			&played,
-			&liked,
-			&unliked,
-			&folderName,
-			&folderPath,
-		); err != nil {
-			return fmt.Errorf("scan TitleTable row: %w", err)
+		titleID, values, err := refs.scanTitleRow(rows)
+		if err != nil {
+			return err
 		}
 
 		processed++
 
-		// Convert nullable values to appropriate Go / SQL types
-		titleTypeID := nullInt64OrNil(titleType)
-		startYear := nullInt64OrNil(titleYear)
-		runtimeMinutes := nullInt64OrNil(titleLength)
-		primaryCountryID := nullInt64OrNil(titleCountry)
-		posterURLVal := nullStringOrNil(posterURL)
-		metacriticVal := nullInt64OrNil(metacritic)
-		revenueVal := nullInt64OrNil(revenue)
-		imdbRatingVal := nullFloat64OrNil(imdbRating)
-		imdbVotesVal := nullInt64OrNil(imdbVotes)
-		popularityVal := nullInt64OrNil(popularity)
-		// parentID is handled in a second pass, so we don't set parent_title_id here
-		seasonNumber := parseSeasonToInt64(episodeSeason)
-		episodeNumberVal := nullInt64OrNil(episodeNumber)
-		totalSeasonsVal := nullInt64OrNil(totalSeasons)
-		totalEpisodesVal := nullInt64OrNil(totalEpisodes)
-		dateReleasedVal := nullTimeOrNil(dateReleased)
-
-		// date_updated is NOT NULL in the new schema.
-		// If DateUpdated is NULL, we fall back to DateAdded.
-		dateUpdatedVal := dateAdded
-		if dateUpdated.Valid {
-			dateUpdatedVal = dateUpdated.Time
-		}
-
-		isAvailable := boolOrFalse(available)
-
-		// Ensure counts are never NULL to satisfy NOT NULL
-		viewedCount := int64(0)
-		if viewed.Valid {
-			viewedCount = viewed.Int64
-		}
-		playedCount := int64(0)
-		if played.Valid {
-			playedCount = played.Int64
-		}
-		likedCount := int64(0)
-		if liked.Valid {
-			likedCount = liked.Int64
-		}
-		dislikedCount := int64(0)
-		if unliked.Valid {
-			dislikedCount = unliked.Int64
-		}
-
-		folderNameVal := nullStringOrNil(folderName)
-		folderPathVal := nullStringOrNil(folderPath)
-
-		if _, err := stmt.ExecContext(
-			ctx,
-			titleID,              // id
-			titleTypeID,          // title_type_id
-			titleName,            // primary_title
-			originalTitle.String, // original_title ("" if NULL)
-			startYear,            // start_year
-			runtimeMinutes,       // runtime_minutes
-			primaryCountryID,     // primary_country_id
-			posterURLVal,         // poster_url
-			metacriticVal,        // metacritic_rating
-			revenueVal,           // revenue
-			imdbRatingVal,        // imdb_rating
-			imdbVotesVal,         // imdb_votes
-			popularityVal,        // popularity
-			nil,                  // parent_title_id (backfilled later)
-			seasonNumber,         // season_number
-			episodeNumberVal,     // episode_number
-			totalSeasonsVal,      // total_seasons
-			totalEpisodesVal,     // total_episodes
-			dateReleasedVal,      // date_released
-			dateAdded,            // date_added
-			dateUpdatedVal,       // date_updated (never NULL)
-			isAvailable,          // is_available
-			viewedCount,          // viewed_count
-			playedCount,          // played_count
-			likedCount,           // liked_count
-			dislikedCount,        // disliked_count
-			folderNameVal,        // folder_name
-			folderPathVal,        // folder_path
-		); err != nil {
-			return fmt.Errorf("insert title id=%d: %w", titleID, err)
+		if err := w.add(ctx, titleID, values...); err != nil {
+			cp.saveOnError(ctx, w.lastKey, resumedFrom+w.done())
+			return fmt.Errorf("insert title: %w", err)
 		}
 
 		inserted++
+		lastKey = titleID
 
 		if inserted%500000 == 0 {
 			percent := float64(inserted) * 100.0 / float64(total)
-			log.Printf("migrateTitles: inserted/updated %d/%d titles (%.1f%%)", inserted, total, percent)
+			logProgress("title", processed, 0, start,
+				"migrateTitles: inserted/updated %d/%d titles (%.1f%%)", inserted, total, percent)
 		}
 	}
 
 	if err := rows.Err(); err != nil {
+		if err := w.flush(ctx); err == nil {
+			cp.saveOnError(ctx, w.lastKey, resumedFrom+w.done())
+		}
 		return fmt.Errorf("iterate TitleTable rows: %w", err)
 	}
+	if err := w.close(ctx); err != nil {
+		cp.saveOnError(ctx, w.lastKey, resumedFrom+w.done())
+		return fmt.Errorf("insert title: %w", err)
+	}
+
+	if err := cp.complete(ctx, lastKey, inserted); err != nil {
+		return err
+	}
 
 	percent := float64(inserted)
 	if total > 0 {
 		percent = percent * 100.0 / float64(total)
 	}
 	log.Printf("migrateTitles: inserted/updated %d/%d titles (%.1f%%)", inserted, total, percent)
-	log.Printf("--- Done title: %d rows processed in %s ---", processed, time.Since(start))
+	logStepDone("title", processed, 0, start, "--- Done title: %d rows processed in %s (%.0f rows/s) ---",
+		processed, time.Since(start), rowsPerSecond(processed, time.Since(start)))
 
 	return nil
 }
 
+// titleRefMaps are the id_map lookups scanTitleRow translates TitleTable
+// reference columns through.
+type titleRefMaps struct {
+	country, category map[int32]int16
+}
+
+func loadTitleRefMaps(ctx context.Context, newDB *sql.DB) (titleRefMaps, error) {
+	var m titleRefMaps
+	var err error
+	if m.country, err = loadIDMap32to16(ctx, newDB, "country"); err != nil {
+		return m, fmt.Errorf("load country ID map: %w", err)
+	}
+	if m.category, err = loadIDMap32to16(ctx, newDB, "category"); err != nil {
+		return m, fmt.Errorf("load category ID map: %w", err)
+	}
+	return m, nil
+}
+
+// mapTitleRef translates the old reference id v in column of title titleID
+// through ids. An id with no mapping is written as NULL, with a WARN.
+func mapTitleRef(titleID int64, column string, v sql.NullInt64, ids map[int32]int16) interface{} {
+	if !v.Valid {
+		return nil
+	}
+	id, ok := ids[int32(v.Int64)]
+	if !ok {
+		log.Printf("WARN: title %d: %s %d has no id_map row; writing NULL", titleID, column, v.Int64)
+		return nil
+	}
+	return int64(id)
+}
+
+// scanTitleRow reads one titleSelectSQL row and converts it to the 31 values of
+// titleInsertColumns, in order. Shared by the row-by-row and COPY paths.
+func (m titleRefMaps) scanTitleRow(rows *sql.Rows) (int64, []interface{}, error) {
+	var (
+		titleID       int64
+		titleType     sql.NullInt64
+		titleName     string
+		originalTitle sql.NullString
+		titleYear     sql.NullInt64
+		titleLength   sql.NullInt64
+		titleCountry  sql.NullInt64
+		posterURL     sql.NullString
+		metacritic    sql.NullInt64
+		revenue       sql.NullInt64
+		imdbRating    sql.NullFloat64
+		imdbVotes     sql.NullInt64
+		popularity    sql.NullInt64
+		parentID      sql.NullInt64
+		episodeSeason sql.NullString
+		episodeNumber sql.NullInt64
+		totalSeasons  sql.NullInt64
+		totalEpisodes sql.NullInt64
+		dateReleased  sql.NullTime
+		dateAdded     time.Time
+		dateUpdated   sql.NullTime
+		available     sql.NullBool
+		viewed        sql.NullInt64
+		played        sql.NullInt64
+		liked         sql.NullInt64
+		unliked       sql.NullInt64
+		folderName    sql.NullString
+		folderPath    sql.NullString
+		summary       sql.NullString
+		storyLine     sql.NullString
+		titleCategory sql.NullInt64
+	)
+
+	if err := rows.Scan(
+		&titleID,
+		&titleType,
+		&titleName,
+		&originalTitle,
+		&titleYear,
+		&titleLength,
+		&titleCountry,
+		&posterURL,
+		&metacritic,
+		&revenue,
+		&imdbRating,
+		&imdbVotes,
+		&popularity,
+		&parentID,
+		&episodeSeason,
+		&episodeNumber,
+		&totalSeasons,
+		&totalEpisodes,
+		&dateReleased,
+		&dateAdded,
+		&dateUpdated,
+		&available,
+		&viewed,
+		&played,
+		&liked,
+		&unliked,
+		&folderName,
+		&folderPath,
+		&summary,
+		&storyLine,
+		&titleCategory,
+	); err != nil {
+		return 0, nil, fmt.Errorf("scan TitleTable row: %w", err)
+	}
+
+	// Convert nullable values to appropriate Go / SQL types
+	titleTypeID := nullInt64OrNil(titleType)
+	startYear := nullInt64OrNil(titleYear)
+	runtimeMinutes := nullInt64OrNil(titleLength)
+	primaryCountryID := mapTitleRef(titleID, "TitleCountry", titleCountry, m.country)
+	posterURLVal := nullStringOrNil(posterURL)
+	metacriticVal := nullInt64OrNil(metacritic)
+	revenueVal := nullInt64OrNil(revenue)
+	imdbRatingVal := nullFloat64OrNil(imdbRating)
+	imdbVotesVal := nullInt64OrNil(imdbVotes)
+	popularityVal := nullInt64OrNil(popularity)
+	// parentID is handled in a second pass, so we don't set parent_title_id here
+	seasonNumber := parseSeasonToInt64(episodeSeason)
+	episodeNumberVal := nullInt64OrNil(episodeNumber)
+	totalSeasonsVal := nullInt64OrNil(totalSeasons)
+	totalEpisodesVal := nullInt64OrNil(totalEpisodes)
+	dateReleasedVal := nullTimeOrNil(dateReleased)
+
+	// date_updated is NOT NULL in the new schema.
+	// If DateUpdated is NULL, we fall back to DateAdded.
+	dateUpdatedVal := dateAdded
+	if dateUpdated.Valid {
+		dateUpdatedVal = dateUpdated.Time
+	}
+
+	isAvailable := boolOrFalse(available)
+
+	// Ensure counts are never NULL to satisfy NOT NULL
+	viewedCount := int64(0)
+	if viewed.Valid {
+		viewedCount = viewed.Int64
+	}
+	playedCount := int64(0)
+	if played.Valid {
+		playedCount = played.Int64
+	}
+	likedCount := int64(0)
+	if liked.Valid {
+		likedCount = liked.Int64
+	}
+	dislikedCount := int64(0)
+	if unliked.Valid {
+		dislikedCount = unliked.Int64
+	}
+
+	folderNameVal := nullStringOrNil(folderName)
+	folderPathVal := nullStringOrNil(folderPath)
+	plotVal := normalizeTextOrNil(summary)
+	storylineVal := normalizeTextOrNil(storyLine)
+	categoryIDVal := mapTitleRef(titleID, "TitleCategory", titleCategory, m.category)
+
+	return titleID, []interface{}{
+		titleID,              // id
+		titleTypeID,          // title_type_id
+		titleName,            // primary_title
+		originalTitle.String, // original_title ("" if NULL)
+		startYear,            // start_year
+		runtimeMinutes,       // runtime_minutes
+		primaryCountryID,     // primary_country_id
+		posterURLVal,         // poster_url
+		metacriticVal,        // metacritic_rating
+		revenueVal,           // revenue
+		imdbRatingVal,        // imdb_rating
+		imdbVotesVal,         // imdb_votes
+		popularityVal,        // popularity
+		nil,                  // parent_title_id (backfilled later)
+		seasonNumber,         // season_number
+		episodeNumberVal,     // episode_number
+		totalSeasonsVal,      // total_seasons
+		totalEpisodesVal,     // total_episodes
+		dateReleasedVal,      // date_released
+		dateAdded,            // date_added
+		dateUpdatedVal,       // date_updated (never NULL)
+		isAvailable,          // is_available
+		viewedCount,          // viewed_count
+		playedCount,          // played_count
+		likedCount,           // liked_count
+		dislikedCount,        // disliked_count
+		folderNameVal,        // folder_name
+		folderPathVal,        // folder_path
+		plotVal,              // plot
+		storylineVal,         // storyline
+		categoryIDVal,        // category_id
+	}, nil
+}
+
+// copyTitles is the -mode=copy path for migrateTitles. As in the row path,
+// parent_title_id is left to backfillTitleParents.
+func copyTitles(ctx context.Context, oldDB, newDB *sql.DB, refs titleRefMaps) error {
+	return runCopyJob(ctx, oldDB, newDB, loadJob{
+		target:     "title",
+		columns:    titleInsertColumns,
+		keyColumns: []string{"id"},
+		noUpdate:   []string{"parent_title_id"},
+		srcQuery:   titleSelectSQL,
+		srcArgs:    []interface{}{0},
+		scan: func(rows *sql.Rows) ([]interface{}, bool, error) {
+			_, values, err := refs.scanTitleRow(rows)
+			return values, err == nil, err
+		},
+	}, nil)
+}
+
 // backfillTitleParents runs AFTER all titles are inserted.
 // It reads (TitleID, ParentID) from old TitleTable and updates title.parent_title_id.
 func backfillTitleParents(ctx context.Context, oldDB, newDB *sql.DB) error {
@@ -402,15 +566,14 @@ func backfillTitleParents(ctx context.Context, oldDB, newDB *sql.DB) error {
 	}
 	defer rows.Close()
 
-	stmt, err := newDB.PrepareContext(ctx, `
+	w, err := newBatchWriter(ctx, newDB, "core-title", "title.parent_title_id", []string{`
 		UPDATE title
 		SET parent_title_id = $2
 		WHERE id = $1
-	`)
+	`}, "id", "parent_title_id")
 	if err != nil {
-		return fmt.Errorf("prepare UPDATE title.parent_title_id: %w", err)
+		return err
 	}
-	defer stmt.Close()
 
 	start := time.Now()
 	var processed int64
@@ -429,14 +592,15 @@ func backfillTitleParents(ctx context.Context, oldDB, newDB *sql.DB) error {
 			continue
 		}
 
-		if _, err := stmt.ExecContext(ctx, titleID, parentID); err != nil {
-			return fmt.Errorf("update parent_title_id for title id=%d: %w", titleID, err)
+		if err := w.add(ctx, titleID, titleID, parentID); err != nil {
+			return fmt.Errorf("update parent_title_id: %w", err)
 		}
 
 		processed++
 		if processed%50000 == 0 {
 			pct := float64(processed) * 100.0 / float64(total)
-			log.Printf("migrateTitles: inserted/updated %d/%d titles (%.1f%%)", processed, total, pct)
+			logProgress("title.parent_title_id", processed, 0, start,
+				"migrateTitles: inserted/updated %d/%d titles (%.1f%%)", processed, total, pct)
 		}
 
 	}
@@ -444,12 +608,16 @@ func backfillTitleParents(ctx context.Context, oldDB, newDB *sql.DB) error {
 	if err := rows.Err(); err != nil {
 		return fmt.Errorf("iterate ParentID rows: %w", err)
 	}
+	if err := w.close(ctx); err != nil {
+		return fmt.Errorf("update parent_title_id: %w", err)
+	}
 
 	percent := float64(processed)
 	if total > 0 {
 		percent = percent * 100.0 / float64(total)
 	}
-	log.Printf("backfillTitleParents: updated %d/%d titles (%.1f%%) in %s", processed, total, percent, time.Since(start))
+	logStepDone("title.parent_title_id", processed, 0, start,
+		"backfillTitleParents: updated %d/%d titles (%.1f%%) in %s", processed, total, percent, time.Since(start))
 
 	return nil
 }
@@ -488,6 +656,58 @@ func nullStringOrNil(n sql.NullString) interface{} {
 	return s
 }
 
+// normalizeTextOrNil cleans multi-line free text written by the LabVIEW apps:
+// invalid UTF-8 and NUL bytes are dropped, CR/LF and lone CR become LF,
+// trailing spaces are trimmed per line and runs of blank lines collapse to one.
+func normalizeTextOrNil(n sql.NullString) interface{} {
+	if !n.Valid {
+		return nil
+	}
+	s := strings.ToValidUTF8(n.String, "")
+	s = strings.ReplaceAll(s, "\x00", "")
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.ReplaceAll(s, "\r", "\n")
+
+	lines := strings.Split(s, "\n")
+	out := make([]string, 0, len(lines))
+	blank := false
+	for _, line := range lines {
+		line = strings.TrimRight(line, " \t")
+		if line == "" {
+			if blank {
+				continue
+			}
+			blank = true
+		} else {
+			blank = false
+		}
+		out = append(out, line)
+	}
+
+	s = strings.TrimSpace(strings.Join(out, "\n"))
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// normalizeLineOrNil is normalizeTextOrNil for single-line values (URLs, names):
+// any CR/LF is treated as stray and removed.
+func normalizeLineOrNil(n sql.NullString) interface{} {
+	if !n.Valid {
+		return nil
+	}
+	s := strings.ToValidUTF8(n.String, "")
+	s = strings.ReplaceAll(s, "\x00", "")
+	s = strings.ReplaceAll(s, "\r", "")
+	s = strings.ReplaceAll(s, "\n", "")
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
 // parseSeasonToInt64 tries to extract an integer season from strings like "1", "S1", "Season 1".
 func parseSeasonToInt64(n sql.NullString) interface{} {
 	if !n.Valid {
@@ -0,0 +1,503 @@
+// cmd/migrate-old-db/phase_episode_links.go
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// MigrateEpisodeLinksPhase runs the "episode-links" phase:
+//
+//	TitleTable.(PreviousTitleID|NextTitleID) -> title.next_episode_id
+//
+// The old player walked episodes through an explicit linked list. Before the
+// links are copied, they are validated against the order derived from
+// (season_number, episode_number) under each parent_title_id. The report says
+// whether the links are fully derivable; they are kept either way, since the
+// old library has hand-edited links (specials, split episodes, ...).
+//
+// Must run after core-title (parent_title_id has to be backfilled).
+func MigrateEpisodeLinksPhase(ctx context.Context, oldDB, newDB *sql.DB, dryRun bool) error {
+	start := time.Now()
+	log.Printf("=== Starting migration phase=\"episode-links\" dryRun=%v ===", dryRun)
+
+	episodes, err := loadNewEpisodes(ctx, newDB)
+	if err != nil {
+		return fmt.Errorf("loadNewEpisodes: %w", err)
+	}
+
+	links, err := loadOldEpisodeLinks(ctx, oldDB)
+	if err != nil {
+		return fmt.Errorf("loadOldEpisodeLinks: %w", err)
+	}
+
+	titleIDs, err := loadNewTitleIDSet(ctx, newDB)
+	if err != nil {
+		return err
+	}
+
+	derived := deriveNextEpisodes(episodes)
+	next := reportEpisodeLinks(episodes, links, derived, titleIDs)
+
+	if err := writeNextEpisodeLinks(ctx, newDB, next, dryRun); err != nil {
+		return fmt.Errorf("writeNextEpisodeLinks: %w", err)
+	}
+
+	log.Printf("=== Migration phase=\"episode-links\" completed successfully in %s ===", time.Since(start))
+	return nil
+}
+
+type episodeRow struct {
+	id       int64
+	parentID int64
+	season   sql.NullInt64
+	episode  sql.NullInt64
+}
+
+type episodeLink struct {
+	prev sql.NullInt64
+	next sql.NullInt64
+}
+
+// loadNewEpisodes returns every new title that has a parent, keyed by id.
+func loadNewEpisodes(ctx context.Context, newDB *sql.DB) (map[int64]episodeRow, error) {
+	rows, err := newDB.QueryContext(ctx, `
+		SELECT id, parent_title_id, season_number, episode_number
+		FROM title
+		WHERE parent_title_id IS NOT NULL
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("select new episodes: %w", err)
+	}
+	defer rows.Close()
+
+	out := make(map[int64]episodeRow)
+	for rows.Next() {
+		var r episodeRow
+		if err := rows.Scan(&r.id, &r.parentID, &r.season, &r.episode); err != nil {
+			return nil, fmt.Errorf("scan new episode: %w", err)
+		}
+		out[r.id] = r
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate new episodes: %w", err)
+	}
+
+	log.Printf("loadNewEpisodes: %d titles with a parent_title_id", len(out))
+	return out, nil
+}
+
+// loadOldEpisodeLinks reads PreviousTitleID / NextTitleID. Zero and negative
+// values are "no link" in the old DB.
+func loadOldEpisodeLinks(ctx context.Context, oldDB *sql.DB) (map[int64]episodeLink, error) {
+	rows, err := oldDB.QueryContext(ctx, `
+		SELECT
+			"TitleID",
+			NULLIF(GREATEST("PreviousTitleID", 0), 0),
+			NULLIF(GREATEST("NextTitleID", 0), 0)
+		FROM "Tables"."TitleTable"
+		WHERE "PreviousTitleID" > 0 OR "NextTitleID" > 0
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("select TitleTable links: %w", err)
+	}
+	defer rows.Close()
+
+	out := make(map[int64]episodeLink)
+	for rows.Next() {
+		var id int64
+		var l episodeLink
+		if err := rows.Scan(&id, &l.prev, &l.next); err != nil {
+			return nil, fmt.Errorf("scan TitleTable link: %w", err)
+		}
+		out[id] = l
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate TitleTable links: %w", err)
+	}
+
+	log.Printf("loadOldEpisodeLinks: %d TitleTable rows have a PreviousTitleID or NextTitleID", len(out))
+	return out, nil
+}
+
+// deriveNextEpisodes orders the episodes of each parent by (season, episode)
+// and returns id -> next id. The chain runs across seasons (last episode of
+// season 1 -> first episode of season 2). Episodes without a season or episode
+// number can't be placed and are left out.
+func deriveNextEpisodes(episodes map[int64]episodeRow) map[int64]int64 {
+	byParent := make(map[int64][]episodeRow)
+	for _, e := range episodes {
+		if !e.season.Valid || !e.episode.Valid {
+			continue
+		}
+		byParent[e.parentID] = append(byParent[e.parentID], e)
+	}
+
+	next := make(map[int64]int64)
+	for _, list := range byParent {
+		sortEpisodes(list)
+		for i := 0; i+1 < len(list); i++ {
+			next[list[i].id] = list[i+1].id
+		}
+	}
+	return next
+}
+
+func sortEpisodes(list []episodeRow) {
+	sort.Slice(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		if a.season.Int64 != b.season.Int64 {
+			return a.season.Int64 < b.season.Int64
+		}
+		if a.episode.Int64 != b.episode.Int64 {
+			return a.episode.Int64 < b.episode.Int64
+		}
+		return a.id < b.id
+	})
+}
+
+// reportEpisodeLinks logs how the old links compare with the derived order and
+// returns the links worth keeping: id -> next id, both ends present in title.
+//
+// Precedence, applied in ascending TitleID order so every run keeps the same
+// links: a title's own NextTitleID wins; a title without one takes the reverse
+// of a PreviousTitleID pointing at it, and when several titles name the same
+// previous title the lowest TitleID wins (the others count as conflicts).
+func reportEpisodeLinks(
+	episodes map[int64]episodeRow,
+	links map[int64]episodeLink,
+	derived map[int64]int64,
+	titleIDs map[int64]struct{},
+) map[int64]int64 {
+	log.Println("--- Validating TitleTable PreviousTitleID/NextTitleID against season/episode order ---")
+
+	var (
+		dangling      int64 // target not in new title
+		selfLinks     int64
+		nonReciprocal int64 // A.next = B but B.prev != A
+		fromPrevOnly  int64
+		prevConflicts int64 // two titles claim the same previous title
+	)
+
+	linkIDs := sortedLinkIDs(links)
+
+	next := make(map[int64]int64)
+	for _, id := range linkIDs {
+		l := links[id]
+		if _, ok := titleIDs[id]; !ok {
+			continue
+		}
+		if !l.next.Valid {
+			continue
+		}
+		target := l.next.Int64
+		switch {
+		case target == id:
+			selfLinks++
+		case !hasID(titleIDs, target):
+			dangling++
+			if dangling <= 20 {
+				log.Printf("WARN: reportEpisodeLinks: TitleID=%d NextTitleID=%d is not in title", id, target)
+			}
+		default:
+			next[id] = target
+			if back, ok := links[target]; !ok || !back.prev.Valid || back.prev.Int64 != id {
+				nonReciprocal++
+			}
+		}
+	}
+
+	for _, id := range linkIDs {
+		l := links[id]
+		if !l.prev.Valid || !hasID(titleIDs, id) {
+			continue
+		}
+		prev := l.prev.Int64
+		if prev == id {
+			selfLinks++
+			continue
+		}
+		if !hasID(titleIDs, prev) {
+			dangling++
+			if dangling <= 20 {
+				log.Printf("WARN: reportEpisodeLinks: TitleID=%d PreviousTitleID=%d is not in title", id, prev)
+			}
+			continue
+		}
+		if existing, ok := next[prev]; ok {
+			if existing != id {
+				prevConflicts++
+			}
+			continue
+		}
+		if own, ok := links[prev]; ok && own.next.Valid {
+			// prev has its own (invalid) NextTitleID, don't second-guess it
+			continue
+		}
+		next[prev] = id
+		fromPrevOnly++
+	}
+
+	var (
+		matches     int64
+		differs     int64
+		crossParent int64
+		notEpisode  int64
+		missing     int64 // derived link with no explicit link
+	)
+	for _, id := range sortedNextIDs(next) {
+		target := next[id]
+		e, okE := episodes[id]
+		t, okT := episodes[target]
+		if !okE || !okT {
+			notEpisode++
+			continue
+		}
+		if e.parentID != t.parentID {
+			crossParent++
+		}
+		if d, ok := derived[id]; ok && d == target {
+			matches++
+			continue
+		}
+		differs++
+		if differs <= 20 {
+			log.Printf("WARN: reportEpisodeLinks: title id=%d (S%s E%s) -> next %d (S%s E%s), season/episode order says %s",
+				id, fmtNullInt(e.season), fmtNullInt(e.episode),
+				target, fmtNullInt(t.season), fmtNullInt(t.episode), fmtDerived(derived, id))
+		}
+	}
+	for id := range derived {
+		if _, ok := next[id]; !ok {
+			missing++
+		}
+	}
+
+	log.Printf("reportEpisodeLinks: %d links kept (%d from PreviousTitleID only); dropped %d dangling, %d self-links; %d not reciprocal, %d PreviousTitleID conflicts",
+		len(next), fromPrevOnly, dangling, selfLinks, nonReciprocal, prevConflicts)
+	log.Printf("reportEpisodeLinks: vs season/episode order: %d match, %d differ, %d cross-parent, %d involve a title without parent, %d derived links have no explicit link",
+		matches, differs, crossParent, notEpisode, missing)
+
+	reportEpisodeChains(episodes, next)
+	reportEpisodeGaps(episodes)
+
+	if differs == 0 && notEpisode == 0 && missing == 0 {
+		log.Println("reportEpisodeLinks: every explicit link matches season/episode order; next_episode_id is derivable")
+	} else {
+		log.Printf("reportEpisodeLinks: %d links can't be derived from season/episode order; they are kept in title.next_episode_id",
+			differs+notEpisode)
+	}
+
+	return next
+}
+
+// reportEpisodeChains walks the kept links per parent and counts parents whose
+// episodes don't form a single chain, plus cycles.
+func reportEpisodeChains(episodes map[int64]episodeRow, next map[int64]int64) {
+	hasPrev := make(map[int64]bool, len(next))
+	for _, target := range next {
+		hasPrev[target] = true
+	}
+
+	chainsByParent := make(map[int64]int)
+	linkedByParent := make(map[int64]int)
+	var cycles int64
+
+	for id, e := range episodes {
+		if _, linked := next[id]; !linked && !hasPrev[id] {
+			continue
+		}
+		linkedByParent[e.parentID]++
+		if hasPrev[id] {
+			continue
+		}
+		chainsByParent[e.parentID]++
+	}
+
+	// Every node of a pure cycle has a previous node, so no chain starts there.
+	seen := make(map[int64]bool, len(next))
+	for id := range next {
+		if seen[id] || !hasPrev[id] {
+			continue
+		}
+		path := make(map[int64]bool)
+		cur := id
+		for {
+			if path[cur] {
+				cycles++
+				break
+			}
+			if seen[cur] {
+				break
+			}
+			path[cur] = true
+			seen[cur] = true
+			n, ok := next[cur]
+			if !ok {
+				break
+			}
+			cur = n
+		}
+	}
+
+	var broken int64
+	for parentID, n := range chainsByParent {
+		if n <= 1 {
+			continue
+		}
+		broken++
+		if broken <= 20 {
+			log.Printf("WARN: reportEpisodeChains: parent_title_id=%d has %d linked episodes split into %d chains",
+				parentID, linkedByParent[parentID], n)
+		}
+	}
+
+	log.Printf("reportEpisodeChains: %d parents with linked episodes, %d with broken chains, %d cycles",
+		len(linkedByParent), broken, cycles)
+}
+
+// reportEpisodeGaps counts missing episode numbers inside a season, duplicate
+// (season, episode) pairs and episodes that can't be ordered at all.
+func reportEpisodeGaps(episodes map[int64]episodeRow) {
+	type seasonKey struct {
+		parentID int64
+		season   int64
+	}
+	numbers := make(map[seasonKey][]int64)
+	var unordered int64
+
+	for _, e := range episodes {
+		if !e.season.Valid || !e.episode.Valid {
+			unordered++
+			continue
+		}
+		k := seasonKey{e.parentID, e.season.Int64}
+		numbers[k] = append(numbers[k], e.episode.Int64)
+	}
+
+	var gaps, duplicates, seasonsWithGaps int64
+	for k, nums := range numbers {
+		sort.Slice(nums, func(i, j int) bool { return nums[i] < nums[j] })
+		var seasonGaps int64
+		for i := 1; i < len(nums); i++ {
+			switch d := nums[i] - nums[i-1]; {
+			case d == 0:
+				duplicates++
+			case d > 1:
+				seasonGaps += d - 1
+			}
+		}
+		if seasonGaps > 0 {
+			seasonsWithGaps++
+			gaps += seasonGaps
+			if seasonsWithGaps <= 20 {
+				log.Printf("WARN: reportEpisodeGaps: parent_title_id=%d season %d is missing %d episode numbers between %d and %d",
+					k.parentID, k.season, seasonGaps, nums[0], nums[len(nums)-1])
+			}
+		}
+	}
+
+	log.Printf("reportEpisodeGaps: %d seasons, %d with gaps (%d missing episode numbers), %d duplicate season/episode pairs, %d episodes without season/episode",
+		len(numbers), seasonsWithGaps, gaps, duplicates, unordered)
+}
+
+// writeNextEpisodeLinks sets title.next_episode_id for every kept link and
+// clears it on titles that no longer have one (links from an earlier run).
+func writeNextEpisodeLinks(ctx context.Context, newDB *sql.DB, next map[int64]int64, dryRun bool) error {
+	ids := sortedNextIDs(next)
+
+	if dryRun {
+		var stale int64
+		if err := newDB.QueryRowContext(ctx, `
+			SELECT COUNT(*) FROM title
+			WHERE next_episode_id IS NOT NULL AND NOT (id = ANY($1))
+		`, pq.Array(ids)).Scan(&stale); err != nil {
+			return fmt.Errorf("count stale next_episode_id: %w", err)
+		}
+		log.Printf("writeNextEpisodeLinks [DRY-RUN]: would set next_episode_id on %d titles and clear it on %d", len(next), stale)
+		return nil
+	}
+
+	start := time.Now()
+	w, err := newBatchWriter(ctx, newDB, "episode-links", "title.next_episode_id", []string{`
+		UPDATE title
+		SET next_episode_id = $2
+		WHERE id = $1
+	`}, "id", "next_episode_id")
+	if err != nil {
+		return err
+	}
+
+	for i, id := range ids {
+		if err := w.add(ctx, id, id, next[id]); err != nil {
+			return err
+		}
+		if (i+1)%junctionProgressEvery == 0 {
+			logProgress("title.next_episode_id", int64(i+1), 0, start,
+				"writeNextEpisodeLinks: updated %d/%d titles", i+1, len(ids))
+		}
+	}
+
+	if err := w.close(ctx); err != nil {
+		return err
+	}
+
+	res, err := newDB.ExecContext(ctx, `
+		UPDATE title
+		SET next_episode_id = NULL
+		WHERE next_episode_id IS NOT NULL AND NOT (id = ANY($1))
+	`, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("clear stale next_episode_id: %w", err)
+	}
+	cleared, _ := res.RowsAffected()
+
+	logStepDone("title.next_episode_id", int64(len(ids)), 0, start,
+		"--- Done title.next_episode_id: %d titles updated, %d stale links cleared ---", len(ids), cleared)
+	return nil
+}
+
+// sortedLinkIDs returns the TitleIDs of links in ascending order.
+func sortedLinkIDs(links map[int64]episodeLink) []int64 {
+	ids := make([]int64, 0, len(links))
+	for id := range links {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// sortedNextIDs returns the title ids that have a kept link, in ascending order.
+func sortedNextIDs(next map[int64]int64) []int64 {
+	ids := make([]int64, 0, len(next))
+	for id := range next {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+func hasID(set map[int64]struct{}, id int64) bool {
+	_, ok := set[id]
+	return ok
+}
+
+func fmtNullInt(n sql.NullInt64) string {
+	if !n.Valid {
+		return "?"
+	}
+	return fmt.Sprint(n.Int64)
+}
+
+func fmtDerived(derived map[int64]int64, id int64) string {
+	if d, ok := derived[id]; ok {
+		return fmt.Sprint(d)
+	}
+	return "none"
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+)
+
+func episode(id, parentID int64, season, number interface{}) episodeRow {
+	e := episodeRow{id: id, parentID: parentID}
+	if s, ok := season.(int); ok {
+		e.season = sql.NullInt64{Int64: int64(s), Valid: true}
+	}
+	if n, ok := number.(int); ok {
+		e.episode = sql.NullInt64{Int64: int64(n), Valid: true}
+	}
+	return e
+}
+
+func link(prev, next int64) episodeLink {
+	var l episodeLink
+	if prev > 0 {
+		l.prev = sql.NullInt64{Int64: prev, Valid: true}
+	}
+	if next > 0 {
+		l.next = sql.NullInt64{Int64: next, Valid: true}
+	}
+	return l
+}
+
+func idSet(ids ...int64) map[int64]struct{} {
+	out := make(map[int64]struct{}, len(ids))
+	for _, id := range ids {
+		out[id] = struct{}{}
+	}
+	return out
+}
+
+func TestDeriveNextEpisodes(t *testing.T) {
+	tests := []struct {
+		name     string
+		episodes []episodeRow
+		want     map[int64]int64
+	}{
+		{
+			name: "across seasons",
+			episodes: []episodeRow{
+				episode(13, 1, 2, 1),
+				episode(11, 1, 1, 1),
+				episode(12, 1, 1, 2),
+			},
+			want: map[int64]int64{11: 12, 12: 13},
+		},
+		{
+			name: "unnumbered episodes left out",
+			episodes: []episodeRow{
+				episode(21, 2, 1, 1),
+				episode(22, 2, 1, nil),
+				episode(23, 2, nil, 3),
+				episode(24, 2, 1, 2),
+			},
+			want: map[int64]int64{21: 24},
+		},
+		{
+			name: "duplicate numbers ordered by id",
+			episodes: []episodeRow{
+				episode(32, 3, 1, 1),
+				episode(31, 3, 1, 1),
+			},
+			want: map[int64]int64{31: 32},
+		},
+		{
+			name: "parents kept apart",
+			episodes: []episodeRow{
+				episode(41, 4, 1, 1),
+				episode(51, 5, 1, 2),
+			},
+			want: map[int64]int64{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			episodes := make(map[int64]episodeRow)
+			for _, e := range tt.episodes {
+				episodes[e.id] = e
+			}
+			if got := deriveNextEpisodes(episodes); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("deriveNextEpisodes = %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReportEpisodeLinksPrecedence(t *testing.T) {
+	tests := []struct {
+		name   string
+		links  map[int64]episodeLink
+		titles map[int64]struct{}
+		want   map[int64]int64
+	}{
+		{
+			name:   "own NextTitleID wins over a PreviousTitleID",
+			links:  map[int64]episodeLink{1: link(0, 2), 3: link(1, 0)},
+			titles: idSet(1, 2, 3),
+			want:   map[int64]int64{1: 2},
+		},
+		{
+			name:   "PreviousTitleID only",
+			links:  map[int64]episodeLink{5: link(4, 0)},
+			titles: idSet(4, 5),
+			want:   map[int64]int64{4: 5},
+		},
+		{
+			name:   "PreviousTitleID conflict resolved in TitleID order",
+			links:  map[int64]episodeLink{9: link(6, 0), 7: link(6, 0), 8: link(6, 0)},
+			titles: idSet(6, 7, 8, 9),
+			want:   map[int64]int64{6: 7},
+		},
+		{
+			name:   "previous title with its own dangling NextTitleID is not reversed",
+			links:  map[int64]episodeLink{11: link(0, 99), 12: link(11, 0)},
+			titles: idSet(11, 12),
+			want:   map[int64]int64{},
+		},
+		{
+			name:   "self-links and dangling targets dropped",
+			links:  map[int64]episodeLink{20: link(20, 20), 21: link(98, 99)},
+			titles: idSet(20, 21),
+			want:   map[int64]int64{},
+		},
+		{
+			name:   "links of titles missing in the new DB ignored",
+			links:  map[int64]episodeLink{30: link(0, 31), 32: link(31, 0)},
+			titles: idSet(31, 32),
+			want:   map[int64]int64{31: 32},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := reportEpisodeLinks(map[int64]episodeRow{}, tt.links, map[int64]int64{}, tt.titles)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("reportEpisodeLinks = %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+// cmd/migrate-old-db/phase_finalize.go
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+
+	"github.com/lib/pq"
+)
+
+// MigrateFinalizePhase realigns every identity and serial sequence in the new
+// schema with the IDs the migration inserted explicitly: each sequence is set
+// so its next value is MAX(column)+1. Tables whose sequence was behind (the
+// next generated ID would already exist) are reported.
+func MigrateFinalizePhase(ctx context.Context, oldDB, newDB *sql.DB, dryRun bool) error {
+	log.Printf("=== Starting migration phase=\"finalize\" dryRun=%v ===", dryRun)
+
+	seqs, err := loadSequenceColumns(ctx, newDB)
+	if err != nil {
+		return err
+	}
+	log.Printf("finalize: %d identity/serial columns in schema", len(seqs))
+
+	var behind int
+	for _, s := range seqs {
+		var maxID sql.NullInt64
+		if err := newDB.QueryRowContext(ctx, fmt.Sprintf(`SELECT MAX(%s) FROM %s`,
+			pq.QuoteIdentifier(s.column), pq.QuoteIdentifier(s.table))).Scan(&maxID); err != nil {
+			return fmt.Errorf("max %s.%s: %w", s.table, s.column, err)
+		}
+		var lastValue int64
+		var isCalled bool
+		if err := newDB.QueryRowContext(ctx, fmt.Sprintf(`SELECT last_value, is_called FROM %s`, s.sequence)).
+			Scan(&lastValue, &isCalled); err != nil {
+			return fmt.Errorf("read sequence %s: %w", s.sequence, err)
+		}
+
+		next := lastValue
+		if isCalled {
+			next++
+		}
+		want := maxID.Int64 + 1
+
+		switch {
+		case next <= maxID.Int64:
+			behind++
+			log.Printf("WARN: finalize: %s.%s sequence was behind: next value %d, MAX(%s) = %d",
+				s.table, s.column, next, s.column, maxID.Int64)
+		case next != want:
+			log.Printf("finalize: %s.%s sequence ahead: next value %d, MAX(%s) = %d",
+				s.table, s.column, next, s.column, maxID.Int64)
+		}
+		if next == want {
+			continue
+		}
+
+		if dryRun {
+			log.Printf("finalize [DRY-RUN]: would set %s to %d", s.sequence, want)
+			continue
+		}
+		if _, err := newDB.ExecContext(ctx, `SELECT setval($1::regclass, $2, false)`, s.sequence, want); err != nil {
+			return fmt.Errorf("setval %s: %w", s.sequence, err)
+		}
+		log.Printf("finalize: %s.%s: next value is now %d", s.table, s.column, want)
+	}
+
+	log.Printf("finalize: %d of %d sequences were behind", behind, len(seqs))
+	log.Printf("=== Migration phase=\"finalize\" completed successfully ===")
+	return nil
+}
+
+// sequenceColumn is a column whose default comes from a sequence.
+type sequenceColumn struct {
+	table, column, sequence string
+}
+
+// loadSequenceColumns lists the identity and serial columns of the current
+// schema (public, or the scratch schema of a -dry-run diff).
+func loadSequenceColumns(ctx context.Context, newDB *sql.DB) ([]sequenceColumn, error) {
+	rows, err := newDB.QueryContext(ctx, `
+		SELECT table_name, column_name,
+		       pg_get_serial_sequence(quote_ident(table_schema) || '.' || quote_ident(table_name), column_name)
+		FROM information_schema.columns
+		WHERE table_schema = current_schema()
+		  AND (is_identity = 'YES' OR column_default LIKE 'nextval(%')
+		ORDER BY table_name, column_name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("list sequence columns: %w", err)
+	}
+	defer rows.Close()
+
+	var out []sequenceColumn
+	for rows.Next() {
+		var s sequenceColumn
+		var seq sql.NullString
+		if err := rows.Scan(&s.table, &s.column, &seq); err != nil {
+			return nil, fmt.Errorf("scan sequence column: %w", err)
+		}
+		if !seq.Valid {
+			log.Printf("WARN: finalize: %s.%s has a nextval default but no owned sequence; skipped", s.table, s.column)
+			continue
+		}
+		s.sequence = seq.String
+		out = append(out, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate sequence columns: %w", err)
+	}
+	return out, nil
+}
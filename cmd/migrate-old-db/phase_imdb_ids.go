@@ -0,0 +1,524 @@
+// cmd/migrate-old-db/phase_imdb_ids.go
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// MigrateImdbIDsPhase fills title.imdb_id (tconst) and person.imdb_id (nconst)
+// where they are still NULL:
+//
+//  1. pattern: a tconst in title.poster_url / folder_name, an nconst in
+//     person.image_url;
+//  2. title.basics (-imdb-title-basics): primaryTitle or originalTitle plus
+//     startYear equal to the title's primary/original title and start_year;
+//  3. name.basics (-imdb-name-basics): primaryName equal to the person's name
+//     (and birthYear, when the person has one); several namesakes are narrowed
+//     down to those whose knownForTitles include one of the person's titles.
+//
+// Only a single, unclaimed candidate is written. Everything else (several
+// candidates, an ID already on another row, two rows wanting the same ID) goes
+// to imdb_id_review with the candidates, to be settled by hand.
+func MigrateImdbIDsPhase(ctx context.Context, oldDB, newDB *sql.DB, dryRun bool) error {
+	log.Printf("=== Starting migration phase=\"imdb-ids\" dryRun=%v ===", dryRun)
+
+	type imdbStep struct {
+		name string
+		run  func(ctx context.Context, newDB *sql.DB, b *imdbBackfill) error
+		skip string
+	}
+	entities := []struct {
+		entity string
+		steps  []imdbStep
+	}{
+		{"title", []imdbStep{
+			{name: "pattern", run: matchTitleImdbPatterns},
+			{name: "title.basics", run: matchTitleBasics, skip: skipWithout(*imdbTitleBasics, "-imdb-title-basics")},
+		}},
+		{"person", []imdbStep{
+			{name: "pattern", run: matchPersonImdbPatterns},
+			{name: "name.basics", run: matchNameBasics, skip: skipWithout(*imdbNameBasics, "-imdb-name-basics")},
+		}},
+	}
+
+	for _, e := range entities {
+		b, err := newImdbBackfill(ctx, newDB, e.entity, dryRun)
+		if err != nil {
+			return err
+		}
+		for _, step := range e.steps {
+			if step.skip != "" {
+				log.Printf("imdb-ids: %s %s: skipped (%s)", e.entity, step.name, step.skip)
+				continue
+			}
+			log.Printf("--- imdb-ids: %s %s ---", e.entity, step.name)
+			if err := step.run(ctx, newDB, b); err != nil {
+				return fmt.Errorf("imdb-ids %s %s: %w", e.entity, step.name, err)
+			}
+		}
+	}
+
+	log.Printf("=== Migration phase=\"imdb-ids\" completed successfully ===")
+	return nil
+}
+
+func skipWithout(path, flagName string) string {
+	if path == "" {
+		return "no " + flagName + " file given"
+	}
+	return ""
+}
+
+var (
+	tconstRe = regexp.MustCompile(`(?:^|[^a-z0-9])(tt\d{7,10})(?:[^0-9]|$)`)
+	nconstRe = regexp.MustCompile(`(?:^|[^a-z0-9])(nm\d{7,10})(?:[^0-9]|$)`)
+)
+
+// imdbBackfill tracks, for one entity (title or person), which rows still need
+// an IMDb id and which IDs are taken, and settles the candidates each step
+// proposes.
+type imdbBackfill struct {
+	entity string
+	table  string
+	dryRun bool
+	taken  map[string]int64 // imdb id -> row id that has it (or was given it)
+	done   map[int64]bool   // rows given an id by an earlier step of this run
+}
+
+func newImdbBackfill(ctx context.Context, newDB *sql.DB, entity string, dryRun bool) (*imdbBackfill, error) {
+	b := &imdbBackfill{entity: entity, table: entity, dryRun: dryRun, taken: make(map[string]int64), done: make(map[int64]bool)}
+
+	rows, err := newDB.QueryContext(ctx, fmt.Sprintf(`SELECT id, imdb_id FROM %s WHERE imdb_id IS NOT NULL`, b.table))
+	if err != nil {
+		return nil, fmt.Errorf("select %s imdb ids: %w", entity, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int64
+		var imdbID string
+		if err := rows.Scan(&id, &imdbID); err != nil {
+			return nil, fmt.Errorf("scan %s imdb id: %w", entity, err)
+		}
+		b.taken[imdbID] = id
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate %s imdb ids: %w", entity, err)
+	}
+	log.Printf("imdb-ids: %d %s rows already have an imdb_id", len(b.taken), entity)
+	return b, nil
+}
+
+// imdbReview is one imdb_id_review row.
+type imdbReview struct {
+	id         int64
+	candidates []string
+	reason     string
+}
+
+// settle turns row id -> candidate IDs into writes and reviews, then applies
+// them. A row is only written when it has exactly one candidate, no other
+// row has that ID, and no other row proposed it in the same step.
+func (b *imdbBackfill) settle(ctx context.Context, newDB *sql.DB, source string, proposals map[int64][]string) error {
+	wanted := make(map[string][]int64)
+	for id, cands := range proposals {
+		cands = dedupeStrings(cands)
+		proposals[id] = cands
+		if len(cands) == 1 {
+			wanted[cands[0]] = append(wanted[cands[0]], id)
+		}
+	}
+
+	matches := make(map[int64]string)
+	var reviews []imdbReview
+	ids := make([]int64, 0, len(proposals))
+	for id := range proposals {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	for _, id := range ids {
+		cands := proposals[id]
+		switch {
+		case len(cands) > 1:
+			reviews = append(reviews, imdbReview{id, cands, fmt.Sprintf("%d candidates", len(cands))})
+		case len(wanted[cands[0]]) > 1:
+			reviews = append(reviews, imdbReview{id, cands,
+				fmt.Sprintf("%s proposed for %d %s rows", cands[0], len(wanted[cands[0]]), b.entity)})
+		default:
+			if owner, ok := b.taken[cands[0]]; ok {
+				reviews = append(reviews, imdbReview{id, cands,
+					fmt.Sprintf("%s already on %s id=%d", cands[0], b.entity, owner)})
+				continue
+			}
+			matches[id] = cands[0]
+		}
+	}
+
+	log.Printf("imdb-ids: %s %s: %d rows matched, %d to review", b.entity, source, len(matches), len(reviews))
+	for id, imdbID := range matches {
+		b.taken[imdbID] = id
+		b.done[id] = true
+	}
+	if b.dryRun {
+		log.Printf("imdb-ids [DRY-RUN]: %s %s: would set %d imdb ids and write %d review rows",
+			b.entity, source, len(matches), len(reviews))
+		return nil
+	}
+	if err := b.apply(ctx, newDB, matches); err != nil {
+		return err
+	}
+	return b.saveReviews(ctx, newDB, source, reviews)
+}
+
+func (b *imdbBackfill) apply(ctx context.Context, newDB *sql.DB, matches map[int64]string) error {
+	tx, err := newDB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx (%s.imdb_id): %w", b.table, err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, fmt.Sprintf(`UPDATE %s SET imdb_id = $2 WHERE id = $1 AND imdb_id IS NULL`, b.table))
+	if err != nil {
+		return fmt.Errorf("prepare update %s.imdb_id: %w", b.table, err)
+	}
+	defer stmt.Close()
+	for id, imdbID := range matches {
+		if _, err := stmt.ExecContext(ctx, id, imdbID); err != nil {
+			return fmt.Errorf("update %s id=%d imdb_id=%s: %w", b.table, id, imdbID, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit %s.imdb_id: %w", b.table, err)
+	}
+	return nil
+}
+
+// saveReviews replaces the review rows of this entity and source.
+func (b *imdbBackfill) saveReviews(ctx context.Context, newDB *sql.DB, source string, reviews []imdbReview) error {
+	tx, err := newDB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx (imdb_id_review): %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM imdb_id_review WHERE entity = $1 AND source = $2`, b.entity, source); err != nil {
+		return fmt.Errorf("clear imdb_id_review %s/%s: %w", b.entity, source, err)
+	}
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO imdb_id_review (entity, entity_id, source, candidates, reason)
+		VALUES ($1, $2, $3, $4, $5)
+	`)
+	if err != nil {
+		return fmt.Errorf("prepare insert imdb_id_review: %w", err)
+	}
+	defer stmt.Close()
+	for _, r := range reviews {
+		if _, err := stmt.ExecContext(ctx, b.entity, r.id, source, pq.Array(r.candidates), r.reason); err != nil {
+			return fmt.Errorf("insert imdb_id_review %s id=%d: %w", b.entity, r.id, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit imdb_id_review: %w", err)
+	}
+	return nil
+}
+
+// ======================
+//   PATTERNS
+// ======================
+
+func matchTitleImdbPatterns(ctx context.Context, newDB *sql.DB, b *imdbBackfill) error {
+	return matchImdbPattern(ctx, newDB, b, tconstRe,
+		`SELECT id, COALESCE(poster_url, '') || ' ' || COALESCE(folder_name, '') FROM title WHERE imdb_id IS NULL`)
+}
+
+func matchPersonImdbPatterns(ctx context.Context, newDB *sql.DB, b *imdbBackfill) error {
+	return matchImdbPattern(ctx, newDB, b, nconstRe,
+		`SELECT id, COALESCE(image_url, '') FROM person WHERE imdb_id IS NULL`)
+}
+
+// matchImdbPattern proposes every ID re matches in the text query returns.
+func matchImdbPattern(ctx context.Context, newDB *sql.DB, b *imdbBackfill, re *regexp.Regexp, query string) error {
+	rows, err := newDB.QueryContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("select %s text: %w", b.entity, err)
+	}
+	defer rows.Close()
+
+	proposals := make(map[int64][]string)
+	var scanned int64
+	for rows.Next() {
+		var id int64
+		var text string
+		if err := rows.Scan(&id, &text); err != nil {
+			return fmt.Errorf("scan %s text: %w", b.entity, err)
+		}
+		scanned++
+		for _, m := range re.FindAllStringSubmatch(strings.ToLower(text), -1) {
+			proposals[id] = append(proposals[id], m[1])
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate %s text: %w", b.entity, err)
+	}
+	log.Printf("imdb-ids: %s pattern: %d rows without imdb_id, %d with an id in their text", b.entity, scanned, len(proposals))
+	return b.settle(ctx, newDB, "pattern", proposals)
+}
+
+// ======================
+//   TSV DUMPS
+// ======================
+
+// matchTitleBasics matches titles on (primary or original title, start_year)
+// against title.basics.
+func matchTitleBasics(ctx context.Context, newDB *sql.DB, b *imdbBackfill) error {
+	rows, err := newDB.QueryContext(ctx, `
+		SELECT id, primary_title, COALESCE(original_title, ''), start_year
+		FROM title
+		WHERE imdb_id IS NULL AND start_year IS NOT NULL
+	`)
+	if err != nil {
+		return fmt.Errorf("select titles without imdb_id: %w", err)
+	}
+	byKey := make(map[string][]int64)
+	for rows.Next() {
+		var id int64
+		var primary, original string
+		var year int64
+		if err := rows.Scan(&id, &primary, &original, &year); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan title: %w", err)
+		}
+		if b.done[id] {
+			continue
+		}
+		for _, key := range nameYearKeys(year, primary, original) {
+			byKey[key] = append(byKey[key], id)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate titles: %w", err)
+	}
+	log.Printf("imdb-ids: title.basics: %d name+year keys to look up", len(byKey))
+
+	proposals := make(map[int64][]string)
+	// tconst, titleType, primaryTitle, originalTitle, isAdult, startYear, ...
+	err = readIMDbTSV(*imdbTitleBasics, 6, func(f []string) {
+		year, err := strconv.ParseInt(f[5], 10, 64)
+		if err != nil {
+			return
+		}
+		for _, key := range nameYearKeys(year, f[2], f[3]) {
+			for _, id := range byKey[key] {
+				proposals[id] = append(proposals[id], f[0])
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return b.settle(ctx, newDB, "title.basics", proposals)
+}
+
+// nameYearKeys returns the distinct normalized "name|year" keys of names.
+func nameYearKeys(year int64, names ...string) []string {
+	var keys []string
+	for _, n := range names {
+		if k := matchKey(n); k != "" {
+			keys = append(keys, k+"|"+strconv.FormatInt(year, 10))
+		}
+	}
+	return dedupeStrings(keys)
+}
+
+// nameCandidate is one name.basics row matching a person.
+type nameCandidate struct {
+	nconst   string
+	knownFor []string
+}
+
+// matchNameBasics matches persons on name (and birth_year when known) against
+// name.basics, narrowing namesakes down by knownForTitles.
+func matchNameBasics(ctx context.Context, newDB *sql.DB, b *imdbBackfill) error {
+	rows, err := newDB.QueryContext(ctx, `SELECT id, name, birth_year FROM person WHERE imdb_id IS NULL`)
+	if err != nil {
+		return fmt.Errorf("select persons without imdb_id: %w", err)
+	}
+	byName := make(map[string][]int64)
+	birthYear := make(map[int64]int64)
+	for rows.Next() {
+		var id int64
+		var name string
+		var year sql.NullInt64
+		if err := rows.Scan(&id, &name, &year); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan person: %w", err)
+		}
+		if b.done[id] {
+			continue
+		}
+		if k := matchKey(name); k != "" {
+			byName[k] = append(byName[k], id)
+		}
+		if year.Valid {
+			birthYear[id] = year.Int64
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate persons: %w", err)
+	}
+	log.Printf("imdb-ids: name.basics: %d names to look up", len(byName))
+
+	candidates := make(map[int64][]nameCandidate)
+	// nconst, primaryName, birthYear, deathYear, primaryProfession, knownForTitles
+	err = readIMDbTSV(*imdbNameBasics, 6, func(f []string) {
+		ids := byName[matchKey(f[1])]
+		if len(ids) == 0 {
+			return
+		}
+		year, yearErr := strconv.ParseInt(f[2], 10, 64)
+		c := nameCandidate{nconst: f[0]}
+		if f[5] != `\N` {
+			c.knownFor = strings.Split(f[5], ",")
+		}
+		for _, id := range ids {
+			if want, ok := birthYear[id]; ok && (yearErr != nil || year != want) {
+				continue
+			}
+			candidates[id] = append(candidates[id], c)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	var namesakes []int64
+	for id, cands := range candidates {
+		if len(cands) > 1 {
+			namesakes = append(namesakes, id)
+		}
+	}
+	personTitles, err := loadPersonTconsts(ctx, newDB, namesakes)
+	if err != nil {
+		return err
+	}
+
+	proposals := make(map[int64][]string)
+	for id, cands := range candidates {
+		var all, knownFor []string
+		for _, c := range cands {
+			all = append(all, c.nconst)
+			for _, t := range c.knownFor {
+				if personTitles[id][t] {
+					knownFor = append(knownFor, c.nconst)
+					break
+				}
+			}
+		}
+		if len(all) > 1 && len(knownFor) == 1 {
+			all = knownFor
+		}
+		proposals[id] = all
+	}
+	return b.settle(ctx, newDB, "name.basics", proposals)
+}
+
+// loadPersonTconsts returns, per person, the tconsts of the titles they are
+// cast in.
+func loadPersonTconsts(ctx context.Context, newDB *sql.DB, personIDs []int64) (map[int64]map[string]bool, error) {
+	out := make(map[int64]map[string]bool)
+	if len(personIDs) == 0 {
+		return out, nil
+	}
+	rows, err := newDB.QueryContext(ctx, `
+		SELECT DISTINCT tc.person_id, t.imdb_id
+		FROM title_cast tc
+		JOIN title t ON t.id = tc.title_id
+		WHERE tc.person_id = ANY($1) AND t.imdb_id IS NOT NULL
+	`, pq.Array(personIDs))
+	if err != nil {
+		return nil, fmt.Errorf("select person titles: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int64
+		var tconst string
+		if err := rows.Scan(&id, &tconst); err != nil {
+			return nil, fmt.Errorf("scan person title: %w", err)
+		}
+		if out[id] == nil {
+			out[id] = make(map[string]bool)
+		}
+		out[id][tconst] = true
+	}
+	return out, rows.Err()
+}
+
+// readIMDbTSV streams an IMDb dataset file (plain or .gz), skipping the header
+// and any line with fewer than minFields tab-separated fields.
+func readIMDbTSV(path string, minFields int, fn func(fields []string)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return fmt.Errorf("gunzip %s: %w", path, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 1024*1024), 16*1024*1024)
+	var lines, short int64
+	for sc.Scan() {
+		lines++
+		if lines == 1 {
+			continue // header
+		}
+		fields := strings.Split(sc.Text(), "\t")
+		if len(fields) < minFields {
+			short++
+			continue
+		}
+		fn(fields)
+	}
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("read %s: %w", path, err)
+	}
+	log.Printf("imdb-ids: read %d rows from %s (%d malformed)", lines-1, path, short)
+	return nil
+}
+
+func dedupeStrings(in []string) []string {
+	seen := make(map[string]bool, len(in))
+	out := in[:0]
+	for _, s := range in {
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	return out
+}
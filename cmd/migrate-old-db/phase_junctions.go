@@ -6,36 +6,13 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
-	"strings"
+	"time"
 )
 
 const junctionProgressEvery = 50000
 
-// MigrateJunctionsPhase keeps the old "all basic junctions" behaviour,
-// but in practice you'll usually call the split phases instead.
-func MigrateJunctionsPhase(ctx context.Context, oldDB, newDB *sql.DB, dryRun bool) error {
-    log.Printf("=== Starting migration phase=\"junctions\" dryRun=%v ===", dryRun)
-
-    if err := MigrateJunctionsCountryPhase(ctx, oldDB, newDB, dryRun); err != nil {
-        return fmt.Errorf("junctions-country: %w", err)
-    }
-    if err := MigrateJunctionsLanguagePhase(ctx, oldDB, newDB, dryRun); err != nil {
-        return fmt.Errorf("junctions-language: %w", err)
-    }
-    if err := MigrateJunctionsGenrePhase(ctx, oldDB, newDB, dryRun); err != nil {
-        return fmt.Errorf("junctions-genre: %w", err)
-    }
-    if err := MigrateJunctionsAliasPhase(ctx, oldDB, newDB, dryRun); err != nil {
-        return fmt.Errorf("junctions-alias: %w", err)
-    }
-    if err := MigrateJunctionsCertificatePhase(ctx, oldDB, newDB, dryRun); err != nil {
-        return fmt.Errorf("junctions-certificate: %w", err)
-    }
-
-    log.Printf("=== Migration phase=\"junctions\" completed successfully ===")
-    return nil
-}
-
+// The "junctions" -phase value is a group in phases.go that runs the split
+// phases below in dependency order.
 
 //
 // Split phases
@@ -45,10 +22,10 @@ func MigrateJunctionsPhase(ctx context.Context, oldDB, newDB *sql.DB, dryRun boo
 func MigrateJunctionsCountryPhase(ctx context.Context, oldDB, newDB *sql.DB, dryRun bool) error {
 	log.Printf("=== Starting migration phase=\"junctions-country\" dryRun=%v ===", dryRun)
 
-	log.Printf("--- Building country ID map (old References.\"CountryRef\" -> new country_ref) ---")
-	countryIDMap, err := buildCountryIDMap(ctx, oldDB, newDB)
+	log.Printf("--- Loading country ID map from id_map ---")
+	countryIDMap, err := loadIDMap32to16(ctx, newDB, "country")
 	if err != nil {
-		return fmt.Errorf("buildCountryIDMap: %w", err)
+		return fmt.Errorf("load country ID map: %w", err)
 	}
 
 	if err := migrateTitleCountry(ctx, oldDB, newDB, countryIDMap, dryRun); err != nil {
@@ -59,20 +36,24 @@ func MigrateJunctionsCountryPhase(ctx context.Context, oldDB, newDB *sql.DB, dry
 	return nil
 }
 
-// Language: Lines."LanguageTitleLine" -> title_language
+// Language: Lines."LanguageTitleLine" + TitleTable.TitleLanguage -> title_language
 func MigrateJunctionsLanguagePhase(ctx context.Context, oldDB, newDB *sql.DB, dryRun bool) error {
 	log.Printf("=== Starting migration phase=\"junctions-language\" dryRun=%v ===", dryRun)
 
-	log.Printf("--- Building language ID map (old References.\"LanguageRef\" -> new language_ref) ---")
-	langIDMap, err := buildLanguageIDMap(ctx, oldDB, newDB)
+	log.Printf("--- Loading language ID map from id_map ---")
+	langIDMap, err := loadIDMap32to16(ctx, newDB, "language")
 	if err != nil {
-		return fmt.Errorf("buildLanguageIDMap: %w", err)
+		return fmt.Errorf("load language ID map: %w", err)
 	}
 
 	if err := migrateTitleLanguage(ctx, oldDB, newDB, langIDMap, dryRun); err != nil {
 		return fmt.Errorf("migrateTitleLanguage: %w", err)
 	}
 
+	if err := migrateTitleOriginalLanguage(ctx, oldDB, newDB, langIDMap, dryRun); err != nil {
+		return fmt.Errorf("migrateTitleOriginalLanguage: %w", err)
+	}
+
 	log.Printf("=== Migration phase=\"junctions-language\" completed successfully ===")
 	return nil
 }
@@ -81,10 +62,10 @@ func MigrateJunctionsLanguagePhase(ctx context.Context, oldDB, newDB *sql.DB, dr
 func MigrateJunctionsGenrePhase(ctx context.Context, oldDB, newDB *sql.DB, dryRun bool) error {
 	log.Printf("=== Starting migration phase=\"junctions-genre\" dryRun=%v ===", dryRun)
 
-	log.Printf("--- Building genre ID map (old References.\"GenreRef\" -> new genre_ref) ---")
-	genreIDMap, err := buildGenreIDMap(ctx, oldDB, newDB)
+	log.Printf("--- Loading genre ID map from id_map ---")
+	genreIDMap, err := loadIDMap32to16(ctx, newDB, "genre")
 	if err != nil {
-		return fmt.Errorf("buildGenreIDMap: %w", err)
+		return fmt.Errorf("load genre ID map: %w", err)
 	}
 
 	if err := migrateTitleGenre(ctx, oldDB, newDB, genreIDMap, dryRun); err != nil {
@@ -95,308 +76,72 @@ func MigrateJunctionsGenrePhase(ctx context.Context, oldDB, newDB *sql.DB, dryRu
 	return nil
 }
 
-// Certificate: Lines."CertificateTitleLine" -> title_certificate
+// Certificate: Lines."CertificateTitleLine" + TitleTable.TitleCertificate -> title_certificate
 func MigrateJunctionsCertificatePhase(ctx context.Context, oldDB, newDB *sql.DB, dryRun bool) error {
 	log.Printf("=== Starting migration phase=\"junctions-certificate\" dryRun=%v ===", dryRun)
 
-	log.Printf("--- Building country ID map (for title_certificate) ---")
-	countryIDMap, err := buildCountryIDMap(ctx, oldDB, newDB)
+	log.Printf("--- Loading country ID map from id_map ---")
+	countryIDMap, err := loadIDMap32to16(ctx, newDB, "country")
 	if err != nil {
-		return fmt.Errorf("buildCountryIDMap (for certificate): %w", err)
+		return fmt.Errorf("load country ID map (for certificate): %w", err)
 	}
 
-	log.Printf("--- Building certificate ID map (old References.\"CertificateRef\" -> new certificate_ref) ---")
-	certIDMap, err := buildCertificateIDMap(ctx, oldDB, newDB)
+	log.Printf("--- Loading certificate ID map from id_map ---")
+	certIDMap, err := loadIDMap32to16(ctx, newDB, "certificate")
 	if err != nil {
-		return fmt.Errorf("buildCertificateIDMap: %w", err)
+		return fmt.Errorf("load certificate ID map: %w", err)
 	}
 
 	if err := migrateTitleCertificate(ctx, oldDB, newDB, countryIDMap, certIDMap, dryRun); err != nil {
 		return fmt.Errorf("migrateTitleCertificate: %w", err)
 	}
 
+	if err := migrateTitlePrimaryCertificate(ctx, oldDB, newDB, countryIDMap, certIDMap, dryRun); err != nil {
+		return fmt.Errorf("migrateTitlePrimaryCertificate: %w", err)
+	}
+
 	log.Printf("=== Migration phase=\"junctions-certificate\" completed successfully ===")
 	return nil
 }
 
 //
-// ID map builders
+// New ID sets (old -> new ID maps live in id_map, see idmap.go)
 //
 
-// old: "References"."CountryRef"(CountryID, CountryName, CountryCode)
-// new: country_ref(id, name, iso2_code, iso3_code)
-func buildCountryIDMap(ctx context.Context, oldDB, newDB *sql.DB) (map[int32]int16, error) {
-	type newCountry struct {
-		id   int16
-		name string
-	}
-	newByName := make(map[string]int16)
-
-	rows, err := newDB.QueryContext(ctx, `SELECT id, name FROM country_ref`)
-	if err != nil {
-		return nil, fmt.Errorf("select new country_ref: %w", err)
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var c newCountry
-		if err := rows.Scan(&c.id, &c.name); err != nil {
-			return nil, fmt.Errorf("scan new country_ref: %w", err)
-		}
-		key := strings.ToLower(strings.TrimSpace(c.name))
-		if key != "" {
-			newByName[key] = c.id
-		}
-	}
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("iterate new country_ref: %w", err)
-	}
-
-	result := make(map[int32]int16)
-	var mapped, missing int64
-
-	rows, err = oldDB.QueryContext(ctx, `
-        SELECT "CountryID", "CountryName"
-        FROM "References"."CountryRef"
-    `)
-	if err != nil {
-		return nil, fmt.Errorf("select old CountryRef: %w", err)
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var oldID int32
-		var name string
-		if err := rows.Scan(&oldID, &name); err != nil {
-			return nil, fmt.Errorf("scan old CountryRef: %w", err)
-		}
-		key := strings.ToLower(strings.TrimSpace(name))
-		if newID, ok := newByName[key]; ok {
-			result[oldID] = newID
-			mapped++
-		} else {
-			if missing < 20 {
-				log.Printf("WARN: buildCountryIDMap: no new country_ref for old CountryID=%d name=%q", oldID, name)
-			}
-			missing++
-		}
-	}
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("iterate old CountryRef: %w", err)
-	}
-
-	log.Printf("buildCountryIDMap: mapped %d old countries; %d missing", mapped, missing)
-	return result, nil
+// loadNewTitleIDSet returns every title.id already present in the NEW DB, so
+// junction phases can skip rows pointing at titles that were never migrated.
+func loadNewTitleIDSet(ctx context.Context, newDB *sql.DB) (map[int64]struct{}, error) {
+	return loadNewIDSet(ctx, newDB, "title")
 }
 
-// old: "References"."LanguageRef"(LanguageID, LanguageName, LanguageCode)
-// new: language_ref(id, name, iso_code)
-func buildLanguageIDMap(ctx context.Context, oldDB, newDB *sql.DB) (map[int32]int16, error) {
-	type newLang struct {
-		id   int16
-		name string
-		code string
-	}
-	byCode := make(map[string]int16)
-	byName := make(map[string]int16)
-
-	rows, err := newDB.QueryContext(ctx, `SELECT id, name, iso_code FROM language_ref`)
-	if err != nil {
-		return nil, fmt.Errorf("select new language_ref: %w", err)
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var l newLang
-		if err := rows.Scan(&l.id, &l.name, &l.code); err != nil {
-			return nil, fmt.Errorf("scan new language_ref: %w", err)
-		}
-		codeKey := strings.ToLower(strings.TrimSpace(l.code))
-		nameKey := strings.ToLower(strings.TrimSpace(l.name))
-		if codeKey != "" {
-			byCode[codeKey] = l.id
-		}
-		if nameKey != "" {
-			byName[nameKey] = l.id
-		}
-	}
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("iterate new language_ref: %w", err)
-	}
-
-	result := make(map[int32]int16)
-	var mapped, missing int64
-
-	rows, err = oldDB.QueryContext(ctx, `
-        SELECT "LanguageID", "LanguageName", "LanguageCode"
-        FROM "References"."LanguageRef"
-    `)
-	if err != nil {
-		return nil, fmt.Errorf("select old LanguageRef: %w", err)
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var oldID int32
-		var name, code string
-		if err := rows.Scan(&oldID, &name, &code); err != nil {
-			return nil, fmt.Errorf("scan old LanguageRef: %w", err)
-		}
-		codeKey := strings.ToLower(strings.TrimSpace(code))
-		nameKey := strings.ToLower(strings.TrimSpace(name))
-
-		var newID int16
-		var ok bool
-
-		if codeKey != "" && codeKey != "undefined" {
-			newID, ok = byCode[codeKey]
-		}
-		if !ok && nameKey != "" {
-			newID, ok = byName[nameKey]
-		}
-
-		if ok {
-			result[oldID] = newID
-			mapped++
-		} else {
-			if missing < 20 {
-				log.Printf("WARN: buildLanguageIDMap: no new language_ref.id for old LanguageID=%d name=%q code=%q", oldID, name, code)
-			}
-			missing++
-		}
-	}
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("iterate old LanguageRef: %w", err)
-	}
-
-	log.Printf("buildLanguageIDMap: mapped %d old languages; %d missing", mapped, missing)
-	return result, nil
+// loadNewPersonIDSet returns every person.id already present in the NEW DB.
+func loadNewPersonIDSet(ctx context.Context, newDB *sql.DB) (map[int64]struct{}, error) {
+	return loadNewIDSet(ctx, newDB, "person")
 }
 
-// old: "References"."GenreRef"(GenreID, GenreName)
-// new: genre_ref(id, name)
-func buildGenreIDMap(ctx context.Context, oldDB, newDB *sql.DB) (map[int32]int16, error) {
-	newByName := make(map[string]int16)
-
-	rows, err := newDB.QueryContext(ctx, `SELECT id, name FROM genre_ref`)
-	if err != nil {
-		return nil, fmt.Errorf("select new genre_ref: %w", err)
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var id int16
-		var name string
-		if err := rows.Scan(&id, &name); err != nil {
-			return nil, fmt.Errorf("scan new genre_ref: %w", err)
-		}
-		key := strings.ToLower(strings.TrimSpace(name))
-		if key != "" {
-			newByName[key] = id
-		}
-	}
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("iterate new genre_ref: %w", err)
-	}
-
-	result := make(map[int32]int16)
-	var mapped, missing int64
+func loadNewIDSet(ctx context.Context, newDB *sql.DB, table string) (map[int64]struct{}, error) {
+	log.Printf("--- Loading existing %s ids from NEW DB ---", table)
 
-	rows, err = oldDB.QueryContext(ctx, `
-        SELECT "GenreID", "GenreName"
-        FROM "References"."GenreRef"
-    `)
+	rows, err := newDB.QueryContext(ctx, `SELECT id FROM `+table)
 	if err != nil {
-		return nil, fmt.Errorf("select old GenreRef: %w", err)
+		return nil, fmt.Errorf("select new %s ids: %w", table, err)
 	}
 	defer rows.Close()
 
+	ids := make(map[int64]struct{})
 	for rows.Next() {
-		var oldID int32
-		var name string
-		if err := rows.Scan(&oldID, &name); err != nil {
-			return nil, fmt.Errorf("scan old GenreRef: %w", err)
-		}
-		key := strings.ToLower(strings.TrimSpace(name))
-		if newID, ok := newByName[key]; ok {
-			result[oldID] = newID
-			mapped++
-		} else {
-			if missing < 20 {
-				log.Printf("WARN: buildGenreIDMap: no new genre_ref.id for old GenreID=%d name=%q", oldID, name)
-			}
-			missing++
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan new %s id: %w", table, err)
 		}
+		ids[id] = struct{}{}
 	}
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("iterate old GenreRef: %w", err)
+		return nil, fmt.Errorf("iterate new %s ids: %w", table, err)
 	}
 
-	log.Printf("buildGenreIDMap: mapped %d old genres; %d missing", mapped, missing)
-	return result, nil
-}
-
-// old: "References"."CertificateRef"(CertificateID, CertificateName)
-// new: certificate_ref(id, name, description)
-func buildCertificateIDMap(ctx context.Context, oldDB, newDB *sql.DB) (map[int32]int16, error) {
-	newByName := make(map[string]int16)
-
-	rows, err := newDB.QueryContext(ctx, `SELECT id, name FROM certificate_ref`)
-	if err != nil {
-		return nil, fmt.Errorf("select new certificate_ref: %w", err)
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var id int16
-		var name string
-		if err := rows.Scan(&id, &name); err != nil {
-			return nil, fmt.Errorf("scan new certificate_ref: %w", err)
-		}
-		key := strings.ToLower(strings.TrimSpace(name))
-		if key != "" {
-			newByName[key] = id
-		}
-	}
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("iterate new certificate_ref: %w", err)
-	}
-
-	result := make(map[int32]int16)
-	var mapped, missing int64
-
-	rows, err = oldDB.QueryContext(ctx, `
-        SELECT "CertificateID", "CertificateName"
-        FROM "References"."CertificateRef"
-    `)
-	if err != nil {
-		return nil, fmt.Errorf("select old CertificateRef: %w", err)
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var oldID int32
-		var name string
-		if err := rows.Scan(&oldID, &name); err != nil {
-			return nil, fmt.Errorf("scan old CertificateRef: %w", err)
-		}
-		key := strings.ToLower(strings.TrimSpace(name))
-		if newID, ok := newByName[key]; ok {
-			result[oldID] = newID
-			mapped++
-		} else {
-			if missing < 20 {
-				log.Printf("WARN: buildCertificateIDMap: no new certificate_ref.id for old CertificateID=%d name=%q", oldID, name)
-			}
-			missing++
-		}
-	}
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("iterate old CertificateRef: %w", err)
-	}
-
-	log.Printf("buildCertificateIDMap: mapped %d old certificates; %d missing", mapped, missing)
-	return result, nil
+	log.Printf("loadNewIDSet: loaded %d %s ids", len(ids), table)
+	return ids, nil
 }
 
 //
@@ -423,76 +168,13 @@ func migrateTitleCountry(
 		return nil
 	}
 
-	rows, err := oldDB.QueryContext(ctx, `
-        SELECT "TitleID", "CountryID"
-        FROM "Lines"."CountryTitleLine"
-        ORDER BY "TitleID"
-    `)
-	if err != nil {
-		return fmt.Errorf("query CountryTitleLine: %w", err)
-	}
-	defer rows.Close()
-
-	tx, err := newDB.BeginTx(ctx, &sql.TxOptions{})
-	if err != nil {
-		return fmt.Errorf("begin tx (title_country): %w", err)
-	}
-	stmt, err := tx.PrepareContext(ctx, `
-        INSERT INTO title_country (title_id, country_id)
-        VALUES ($1, $2)
-        ON CONFLICT (title_id, country_id) DO NOTHING
-    `)
-	if err != nil {
-		_ = tx.Rollback()
-		return fmt.Errorf("prepare insert title_country: %w", err)
-	}
-	defer stmt.Close()
-
-	var processed int64
-	var skipped int64
-
-	for rows.Next() {
-		var titleID int32
-		var oldCountryID int32
-		if err := rows.Scan(&titleID, &oldCountryID); err != nil {
-			_ = tx.Rollback()
-			return fmt.Errorf("scan CountryTitleLine: %w", err)
-		}
-
-		newCountryID, ok := countryIDMap[oldCountryID]
-		if !ok {
-			// No mapped country -> skip
-			skipped++
-			continue
-		}
-
-		if _, err := stmt.ExecContext(ctx, titleID, newCountryID); err != nil {
-			_ = tx.Rollback()
-			return fmt.Errorf("insert title_country title_id=%d oldCountryID=%d -> newCountryID=%d: %w",
-				titleID, oldCountryID, newCountryID, err)
-		}
-
-		processed++
-		if processed%junctionProgressEvery == 0 && total > 0 {
-			pct := float64(processed) * 100.0 / float64(total)
-			log.Printf("migrateTitleCountry: inserted %d/%d rows (%.1f%%)", processed, total, pct)
-		}
-	}
-	if err := rows.Err(); err != nil {
-		_ = tx.Rollback()
-		return fmt.Errorf("iterate CountryTitleLine: %w", err)
-	}
-
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("commit title_country: %w", err)
-	}
-	log.Printf("--- Done title_country: %d rows processed, %d skipped (no country mapping) ---", processed, skipped)
-	return nil
+	return runJunctionJob(ctx, oldDB, newDB, "junctions-country", titleCountryJob(countryIDMap), total)
 }
 
 // LanguageTitleLine -> title_language
 // NOTE: old schema has ONLY (LanguageTitleLineID, TitleID, LanguageID).
-//       There is NO "IsOriginalLanguage" column, so we set is_original = false for all rows.
+//       There is NO "IsOriginalLanguage" column, so we set is_original = false for all rows
+//       here; migrateTitleOriginalLanguage sets it afterwards from TitleTable.TitleLanguage.
 func migrateTitleLanguage(
 	ctx context.Context,
 	oldDB, newDB *sql.DB,
@@ -510,71 +192,107 @@ func migrateTitleLanguage(
 		return nil
 	}
 
+	return runJunctionJob(ctx, oldDB, newDB, "junctions-language", titleLanguageJob(langIDMap), total)
+}
+
+// TitleTable.TitleLanguage -> title_language.is_original
+//
+// Runs after migrateTitleLanguage. The original language is upserted, so titles
+// whose LanguageTitleLine rows don't include it still get it. Any other row
+// flagged is_original for the title is cleared, keeping one original per title.
+func migrateTitleOriginalLanguage(
+	ctx context.Context,
+	oldDB, newDB *sql.DB,
+	langIDMap map[int32]int16,
+	dryRun bool,
+) error {
+	var total int64
+	if err := oldDB.QueryRowContext(ctx, `
+        SELECT COUNT(*)
+        FROM "Tables"."TitleTable"
+        WHERE "TitleLanguage" IS NOT NULL
+    `).Scan(&total); err != nil {
+		return fmt.Errorf("count TitleTable.TitleLanguage: %w", err)
+	}
+	log.Printf("migrateTitleOriginalLanguage: %d TitleTable rows have a TitleLanguage", total)
+
+	if dryRun {
+		log.Printf("migrateTitleOriginalLanguage [DRY-RUN]: would flag %d original languages", total)
+		return nil
+	}
+
+	titleIDs, err := loadNewTitleIDSet(ctx, newDB)
+	if err != nil {
+		return err
+	}
+
 	rows, err := oldDB.QueryContext(ctx, `
-        SELECT "TitleID", "LanguageID"
-        FROM "Lines"."LanguageTitleLine"
+        SELECT "TitleID", "TitleLanguage"
+        FROM "Tables"."TitleTable"
+        WHERE "TitleLanguage" IS NOT NULL
         ORDER BY "TitleID"
     `)
 	if err != nil {
-		return fmt.Errorf("query LanguageTitleLine: %w", err)
+		return fmt.Errorf("query TitleTable.TitleLanguage: %w", err)
 	}
 	defer rows.Close()
 
-	tx, err := newDB.BeginTx(ctx, &sql.TxOptions{})
-	if err != nil {
-		return fmt.Errorf("begin tx (title_language): %w", err)
-	}
-	stmt, err := tx.PrepareContext(ctx, `
+	w, err := newBatchWriter(ctx, newDB, "junctions-language", "title_language.is_original", []string{`
+        UPDATE title_language
+        SET is_original = FALSE
+        WHERE title_id = $1 AND language_id <> $2 AND is_original
+    `, `
         INSERT INTO title_language (title_id, language_id, is_original)
-        VALUES ($1, $2, $3)
-        ON CONFLICT (title_id, language_id) DO NOTHING
-    `)
+        VALUES ($1, $2, TRUE)
+        ON CONFLICT (title_id, language_id) DO UPDATE
+        SET is_original = TRUE
+    `}, "title_id", "language_id")
 	if err != nil {
-		_ = tx.Rollback()
-		return fmt.Errorf("prepare insert title_language: %w", err)
+		return err
 	}
-	defer stmt.Close()
 
 	var processed int64
-	var skipped int64
+	var skippedTitle, skippedLang int64
+	start := time.Now()
 
 	for rows.Next() {
-		var titleID int32
+		var titleID int64
 		var oldLangID int32
 		if err := rows.Scan(&titleID, &oldLangID); err != nil {
-			_ = tx.Rollback()
-			return fmt.Errorf("scan LanguageTitleLine: %w", err)
+			return fmt.Errorf("scan TitleTable.TitleLanguage: %w", err)
 		}
 
+		if _, ok := titleIDs[titleID]; !ok {
+			skippedTitle++
+			continue
+		}
 		newLangID, ok := langIDMap[oldLangID]
 		if !ok {
-			skipped++
+			skippedLang++
 			continue
 		}
 
-		isOriginal := false // old DB has no flag; default to false
-
-		if _, err := stmt.ExecContext(ctx, titleID, newLangID, isOriginal); err != nil {
-			_ = tx.Rollback()
-			return fmt.Errorf("insert title_language title_id=%d oldLanguageID=%d -> newLanguageID=%d: %w",
-				titleID, oldLangID, newLangID, err)
+		if err := w.add(ctx, titleID, titleID, newLangID); err != nil {
+			return err
 		}
 
 		processed++
 		if processed%junctionProgressEvery == 0 && total > 0 {
 			pct := float64(processed) * 100.0 / float64(total)
-			log.Printf("migrateTitleLanguage: inserted %d/%d rows (%.1f%%)", processed, total, pct)
+			logProgress("title_language.is_original", processed, skippedTitle+skippedLang, start,
+				"migrateTitleOriginalLanguage: flagged %d/%d titles (%.1f%%)", processed, total, pct)
 		}
 	}
 	if err := rows.Err(); err != nil {
-		_ = tx.Rollback()
-		return fmt.Errorf("iterate LanguageTitleLine: %w", err)
+		return fmt.Errorf("iterate TitleTable.TitleLanguage: %w", err)
 	}
 
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("commit title_language: %w", err)
+	if err := w.close(ctx); err != nil {
+		return err
 	}
-	log.Printf("--- Done title_language: %d rows processed, %d skipped (no language mapping) ---", processed, skipped)
+	logStepDone("title_language.is_original", processed, skippedTitle+skippedLang, start,
+		"--- Done title_language.is_original: %d titles processed, %d skipped (no title=%d, no language mapping=%d) ---",
+		processed, skippedTitle+skippedLang, skippedTitle, skippedLang)
 	return nil
 }
 
@@ -596,70 +314,7 @@ func migrateTitleGenre(
 		return nil
 	}
 
-	rows, err := oldDB.QueryContext(ctx, `
-        SELECT "TitleID", "GenreID"
-        FROM "Lines"."GenreTitleLine"
-        ORDER BY "TitleID"
-    `)
-	if err != nil {
-		return fmt.Errorf("query GenreTitleLine: %w", err)
-	}
-	defer rows.Close()
-
-	tx, err := newDB.BeginTx(ctx, &sql.TxOptions{})
-	if err != nil {
-		return fmt.Errorf("begin tx (title_genre): %w", err)
-	}
-	stmt, err := tx.PrepareContext(ctx, `
-        INSERT INTO title_genre (title_id, genre_id)
-        VALUES ($1, $2)
-        ON CONFLICT (title_id, genre_id) DO NOTHING
-    `)
-	if err != nil {
-		_ = tx.Rollback()
-		return fmt.Errorf("prepare insert title_genre: %w", err)
-	}
-	defer stmt.Close()
-
-	var processed int64
-	var skipped int64
-
-	for rows.Next() {
-		var titleID int32
-		var oldGenreID int32
-		if err := rows.Scan(&titleID, &oldGenreID); err != nil {
-			_ = tx.Rollback()
-			return fmt.Errorf("scan GenreTitleLine: %w", err)
-		}
-
-		newGenreID, ok := genreIDMap[oldGenreID]
-		if !ok {
-			skipped++
-			continue
-		}
-
-		if _, err := stmt.ExecContext(ctx, titleID, newGenreID); err != nil {
-			_ = tx.Rollback()
-			return fmt.Errorf("insert title_genre title_id=%d oldGenreID=%d -> newGenreID=%d: %w",
-				titleID, oldGenreID, newGenreID, err)
-		}
-
-		processed++
-		if processed%junctionProgressEvery == 0 && total > 0 {
-			pct := float64(processed) * 100.0 / float64(total)
-			log.Printf("migrateTitleGenre: inserted %d/%d rows (%.1f%%)", processed, total, pct)
-		}
-	}
-	if err := rows.Err(); err != nil {
-		_ = tx.Rollback()
-		return fmt.Errorf("iterate GenreTitleLine: %w", err)
-	}
-
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("commit title_genre: %w", err)
-	}
-	log.Printf("--- Done title_genre: %d rows processed, %d skipped (no genre mapping) ---", processed, skipped)
-	return nil
+	return runJunctionJob(ctx, oldDB, newDB, "junctions-genre", titleGenreJob(genreIDMap), total)
 }
 
 // CertificateTitleLine -> title_certificate
@@ -681,70 +336,105 @@ func migrateTitleCertificate(
 		return nil
 	}
 
+	return runJunctionJob(ctx, oldDB, newDB, "junctions-certificate", titleCertificateJob(countryIDMap, certIDMap), total)
+}
+
+// TitleTable.TitleCertificate -> title_certificate for the primary country
+//
+// The title-level certificate has no country of its own; it is the rating in
+// the title's primary country (TitleTable.TitleCountry). Titles without a
+// TitleCountry are skipped, as title_certificate needs a country_id.
+func migrateTitlePrimaryCertificate(
+	ctx context.Context,
+	oldDB, newDB *sql.DB,
+	countryIDMap map[int32]int16,
+	certIDMap map[int32]int16,
+	dryRun bool,
+) error {
+	var total, noCountry int64
+	if err := oldDB.QueryRowContext(ctx, `
+        SELECT COUNT(*), COUNT(*) FILTER (WHERE "TitleCountry" IS NULL)
+        FROM "Tables"."TitleTable"
+        WHERE "TitleCertificate" IS NOT NULL
+    `).Scan(&total, &noCountry); err != nil {
+		return fmt.Errorf("count TitleTable.TitleCertificate: %w", err)
+	}
+	log.Printf("migrateTitlePrimaryCertificate: %d TitleTable rows have a TitleCertificate (%d without TitleCountry)", total, noCountry)
+
+	if dryRun {
+		log.Printf("migrateTitlePrimaryCertificate [DRY-RUN]: would process %d rows", total-noCountry)
+		return nil
+	}
+
+	titleIDs, err := loadNewTitleIDSet(ctx, newDB)
+	if err != nil {
+		return err
+	}
+
 	rows, err := oldDB.QueryContext(ctx, `
-        SELECT "TitleID", "CertificateID", "CountryID"
-        FROM "Lines"."CertificateTitleLine"
+        SELECT "TitleID", "TitleCertificate", "TitleCountry"
+        FROM "Tables"."TitleTable"
+        WHERE "TitleCertificate" IS NOT NULL
+          AND "TitleCountry" IS NOT NULL
         ORDER BY "TitleID"
     `)
 	if err != nil {
-		return fmt.Errorf("query CertificateTitleLine: %w", err)
+		return fmt.Errorf("query TitleTable.TitleCertificate: %w", err)
 	}
 	defer rows.Close()
 
-	tx, err := newDB.BeginTx(ctx, &sql.TxOptions{})
-	if err != nil {
-		return fmt.Errorf("begin tx (title_certificate): %w", err)
-	}
-	stmt, err := tx.PrepareContext(ctx, `
+	w, err := newBatchWriter(ctx, newDB, "junctions-certificate", "title_certificate.primary", []string{`
         INSERT INTO title_certificate (title_id, certificate_id, country_id)
         VALUES ($1, $2, $3)
         ON CONFLICT (title_id, certificate_id, country_id) DO NOTHING
-    `)
+    `}, "title_id", "certificate_id", "country_id")
 	if err != nil {
-		_ = tx.Rollback()
-		return fmt.Errorf("prepare insert title_certificate: %w", err)
+		return err
 	}
-	defer stmt.Close()
 
 	var processed int64
-	var skipped int64
+	var skippedTitle, skippedMapping int64
+	start := time.Now()
 
 	for rows.Next() {
-		var titleID int32
-		var oldCertID int32
-		var oldCountryID int32
+		var titleID int64
+		var oldCertID, oldCountryID int32
 		if err := rows.Scan(&titleID, &oldCertID, &oldCountryID); err != nil {
-			_ = tx.Rollback()
-			return fmt.Errorf("scan CertificateTitleLine: %w", err)
+			return fmt.Errorf("scan TitleTable.TitleCertificate: %w", err)
 		}
 
+		if _, ok := titleIDs[titleID]; !ok {
+			skippedTitle++
+			continue
+		}
 		newCertID, okCert := certIDMap[oldCertID]
 		newCountryID, okCountry := countryIDMap[oldCountryID]
 		if !okCert || !okCountry {
-			skipped++
+			skippedMapping++
 			continue
 		}
 
-		if _, err := stmt.ExecContext(ctx, titleID, newCertID, newCountryID); err != nil {
-			_ = tx.Rollback()
-			return fmt.Errorf("insert title_certificate title_id=%d oldCertID=%d->%d oldCountryID=%d->%d: %w",
-				titleID, oldCertID, newCertID, oldCountryID, newCountryID, err)
+		if err := w.add(ctx, titleID, titleID, newCertID, newCountryID); err != nil {
+			return err
 		}
 
 		processed++
 		if processed%junctionProgressEvery == 0 && total > 0 {
 			pct := float64(processed) * 100.0 / float64(total)
-			log.Printf("migrateTitleCertificate: inserted %d/%d rows (%.1f%%)", processed, total, pct)
+			logProgress("title_certificate.primary", processed, skippedTitle+skippedMapping, start,
+				"migrateTitlePrimaryCertificate: processed %d/%d rows (%.1f%%)", processed, total, pct)
 		}
 	}
 	if err := rows.Err(); err != nil {
-		_ = tx.Rollback()
-		return fmt.Errorf("iterate CertificateTitleLine: %w", err)
+		return fmt.Errorf("iterate TitleTable.TitleCertificate: %w", err)
 	}
 
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("commit title_certificate: %w", err)
+	if err := w.close(ctx); err != nil {
+		return err
 	}
-	log.Printf("--- Done title_certificate: %d rows processed, %d skipped (no cert/country mapping) ---", processed, skipped)
+	existing := w.written - w.affected
+	logStepDone("title_certificate.primary", processed, skippedTitle+skippedMapping+noCountry, start,
+		"--- Done title_certificate (primary country): %d rows processed (%d already in CertificateTitleLine), %d skipped (no title=%d, no cert/country mapping=%d, no TitleCountry=%d) ---",
+		processed, existing, skippedTitle+skippedMapping+noCountry, skippedTitle, skippedMapping, noCountry)
 	return nil
 }
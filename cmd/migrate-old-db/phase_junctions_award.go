@@ -0,0 +1,227 @@
+// cmd/migrate-old-db/phase_junctions_award.go
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Award: Lines."AwardTitleLine" -> title_award
+func MigrateJunctionsAwardPhase(ctx context.Context, oldDB, newDB *sql.DB, dryRun bool) error {
+	log.Printf("=== Starting migration phase=\"junctions-award\" dryRun=%v ===", dryRun)
+
+	log.Printf("--- Loading award event ID map from id_map ---")
+	eventIDMap, err := loadIDMap32to32(ctx, newDB, "award_event")
+	if err != nil {
+		return fmt.Errorf("load award_event ID map: %w", err)
+	}
+
+	log.Printf("--- Loading nomination type ID map from id_map ---")
+	nomIDMap, err := loadIDMap16to16(ctx, newDB, "award_nomination_type")
+	if err != nil {
+		return fmt.Errorf("load award_nomination_type ID map: %w", err)
+	}
+
+	if err := migrateTitleAward(ctx, oldDB, newDB, eventIDMap, nomIDMap, dryRun); err != nil {
+		return fmt.Errorf("migrateTitleAward: %w", err)
+	}
+
+	log.Printf("=== Migration phase=\"junctions-award\" completed successfully ===")
+	return nil
+}
+
+// AwardTitleLine -> title_award
+//
+// AwardYear is varchar in the old DB ("2004", "2004/II", "2003-2004", ...).
+// We keep the first 4-digit year; anything else becomes NULL and is reported.
+// title_award is unique on (title, person, event, nomination, year, category,
+// description) with NULLS NOT DISTINCT, so only exact repeats (or rows whose
+// AwardYear both parse to NULL) collapse; they are counted as duplicates
+// instead of failing.
+func migrateTitleAward(
+	ctx context.Context,
+	oldDB, newDB *sql.DB,
+	eventIDMap map[int32]int32,
+	nomIDMap map[int16]int16,
+	dryRun bool,
+) error {
+	var total int64
+	if err := oldDB.QueryRowContext(ctx, `SELECT COUNT(*) FROM "Lines"."AwardTitleLine"`).Scan(&total); err != nil {
+		return fmt.Errorf("count AwardTitleLine: %w", err)
+	}
+	log.Printf("migrateTitleAward: %d rows in Lines.\"AwardTitleLine\"", total)
+
+	if dryRun {
+		log.Printf("migrateTitleAward [DRY-RUN]: would process %d rows", total)
+		return nil
+	}
+
+	titleIDs, err := loadNewTitleIDSet(ctx, newDB)
+	if err != nil {
+		return err
+	}
+	personIDs, err := loadNewPersonIDSet(ctx, newDB)
+	if err != nil {
+		return err
+	}
+	merges, err := loadPersonMerges(ctx, newDB)
+	if err != nil {
+		return err
+	}
+
+	rows, err := oldDB.QueryContext(ctx, `
+        SELECT "TitleID", "EventID", "CastID", "AwardYear", "NominationType", "Description", "Category"
+        FROM "Lines"."AwardTitleLine"
+        ORDER BY "TitleID"
+    `)
+	if err != nil {
+		return fmt.Errorf("query AwardTitleLine: %w", err)
+	}
+	defer rows.Close()
+
+	w, err := newBatchWriter(ctx, newDB, "junctions-award", "title_award", []string{`
+        INSERT INTO title_award (title_id, person_id, event_id, nomination_type_id, award_year, description, category)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)
+        ON CONFLICT ON CONSTRAINT title_award_uq DO NOTHING
+    `}, "title_id", "person_id", "event_id", "nomination_type_id", "award_year", "description", "category")
+	if err != nil {
+		return err
+	}
+
+	var (
+		processed      int64
+		skippedTitle   int64
+		skippedPerson  int64
+		skippedMapping int64
+		badYears       int64
+	)
+	badYearValues := make(map[string]int64)
+	start := time.Now()
+
+	for rows.Next() {
+		var (
+			titleID     int64
+			oldEventID  int32
+			castID      int64
+			awardYear   string
+			oldNomID    int16
+			description sql.NullString
+			category    sql.NullString
+		)
+		if err := rows.Scan(&titleID, &oldEventID, &castID, &awardYear, &oldNomID, &description, &category); err != nil {
+			return fmt.Errorf("scan AwardTitleLine: %w", err)
+		}
+
+		if _, ok := titleIDs[titleID]; !ok {
+			skippedTitle++
+			continue
+		}
+		personID := resolvePerson(merges, castID)
+		if _, ok := personIDs[personID]; !ok {
+			skippedPerson++
+			continue
+		}
+		newEventID, okEvent := eventIDMap[oldEventID]
+		newNomID, okNom := nomIDMap[oldNomID]
+		if !okEvent || !okNom {
+			skippedMapping++
+			continue
+		}
+
+		var year interface{}
+		if y, ok := parseAwardYear(awardYear); ok {
+			year = y
+		} else {
+			badYears++
+			badYearValues[awardYear]++
+		}
+
+		if err := w.add(ctx, titleID,
+			titleID, personID, newEventID, newNomID, year,
+			nullStringOrNil(description), nullStringOrNil(category)); err != nil {
+			return err
+		}
+
+		processed++
+		if processed%junctionProgressEvery == 0 && total > 0 {
+			pct := float64(processed) * 100.0 / float64(total)
+			logProgress("title_award", processed, skippedTitle+skippedPerson+skippedMapping, start,
+				"migrateTitleAward: inserted %d/%d rows (%.1f%%)", processed, total, pct)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate AwardTitleLine: %w", err)
+	}
+
+	if err := w.close(ctx); err != nil {
+		return err
+	}
+	duplicates := w.written - w.affected
+
+	if badYears > 0 {
+		logUnparsedAwardYears(badYearValues, badYears)
+	}
+	logStepDone("title_award", processed, skippedTitle+skippedPerson+skippedMapping, start,
+		"--- Done title_award: %d rows processed (%d already present / collapsed duplicates), %d skipped (no title=%d, no person=%d, no event/nomination mapping=%d), %d with NULL award_year ---",
+		processed, duplicates, skippedTitle+skippedPerson+skippedMapping,
+		skippedTitle, skippedPerson, skippedMapping, badYears)
+	return nil
+}
+
+// parseAwardYear extracts the first 4-digit run from the old varchar AwardYear
+// and accepts it only if it looks like a real award year.
+func parseAwardYear(s string) (int64, bool) {
+	s = strings.TrimSpace(s)
+
+	run := 0
+	for i, r := range s {
+		if r >= '0' && r <= '9' {
+			run++
+			if run == 4 && (i+1 == len(s) || s[i+1] < '0' || s[i+1] > '9') {
+				y, err := strconv.ParseInt(s[i-3:i+1], 10, 64)
+				if err != nil || y < 1900 || y > 2100 {
+					return 0, false
+				}
+				return y, true
+			}
+			continue
+		}
+		run = 0
+	}
+	return 0, false
+}
+
+// logUnparsedAwardYears reports the distinct AwardYear values that could not
+// be parsed, most frequent first.
+func logUnparsedAwardYears(values map[string]int64, total int64) {
+	type yearCount struct {
+		value string
+		count int64
+	}
+	list := make([]yearCount, 0, len(values))
+	for v, c := range values {
+		list = append(list, yearCount{v, c})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].count != list[j].count {
+			return list[i].count > list[j].count
+		}
+		return list[i].value < list[j].value
+	})
+
+	log.Printf("WARN: migrateTitleAward: %d rows (%d distinct values) had an unparseable AwardYear; stored award_year=NULL",
+		total, len(list))
+	for i, yc := range list {
+		if i == 20 {
+			log.Printf("WARN: migrateTitleAward: ... and %d more distinct values", len(list)-20)
+			break
+		}
+		log.Printf("WARN: migrateTitleAward: AwardYear=%q x%d", yc.value, yc.count)
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import "testing"
+
+func TestParseAwardYear(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+		ok   bool
+	}{
+		{"2004", 2004, true},
+		{" 1999 ", 1999, true},
+		{"2003-2004", 2003, true},
+		{"2004/II", 2004, true},
+		{"Festival 1987", 1987, true},
+		{"1899", 0, false},
+		{"2101", 0, false},
+		{"12345", 0, false},
+		{"98", 0, false},
+		{"", 0, false},
+		{"n/a", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseAwardYear(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseAwardYear(%q) = %d, %v; want %d, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+// cmd/migrate-old-db/phase_junctions_cast.go
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+)
+
+// Cast: Lines."CastTitleLine" -> title_cast
+func MigrateJunctionsCastPhase(ctx context.Context, oldDB, newDB *sql.DB, dryRun bool) error {
+	log.Printf("=== Starting migration phase=\"junctions-cast\" dryRun=%v ===", dryRun)
+
+	log.Printf("--- Loading cast role type ID map from id_map ---")
+	roleIDMap, err := loadIDMap16to16(ctx, newDB, "cast_role_type")
+	if err != nil {
+		return fmt.Errorf("load cast_role_type ID map: %w", err)
+	}
+
+	merges, err := loadPersonMerges(ctx, newDB)
+	if err != nil {
+		return err
+	}
+
+	if err := migrateTitleCast(ctx, oldDB, newDB, roleIDMap, merges, dryRun); err != nil {
+		return fmt.Errorf("migrateTitleCast: %w", err)
+	}
+
+	log.Printf("=== Migration phase=\"junctions-cast\" completed successfully ===")
+	return nil
+}
+
+// CastTitleLine -> title_cast
+//
+// Assumes new person.id == old CastTable.CastID and new title.id == old TitleTable.TitleID
+// (as in the core-persons / core-title migrations), except for persons
+// merge-persons merged away, which merges maps. Rows whose person or title
+// never made it into the new DB are skipped and counted instead of failing the FK.
+func migrateTitleCast(
+	ctx context.Context,
+	oldDB, newDB *sql.DB,
+	roleIDMap map[int16]int16,
+	merges map[int64]int64,
+	dryRun bool,
+) error {
+	var total int64
+	if err := oldDB.QueryRowContext(ctx, `SELECT COUNT(*) FROM "Lines"."CastTitleLine"`).Scan(&total); err != nil {
+		return fmt.Errorf("count CastTitleLine: %w", err)
+	}
+	log.Printf("migrateTitleCast: %d rows in Lines.\"CastTitleLine\"", total)
+
+	if dryRun {
+		log.Printf("migrateTitleCast [DRY-RUN]: would process %d rows", total)
+		return nil
+	}
+
+	return runJunctionJob(ctx, oldDB, newDB, "junctions-cast", titleCastJob(roleIDMap, merges), total)
+}
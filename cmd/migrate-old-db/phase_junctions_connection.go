@@ -0,0 +1,219 @@
+// cmd/migrate-old-db/phase_junctions_connection.go
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Connection: Lines."ConnectionTitleLine" -> title_connection
+// Similarity: Lines."SimilaritiesTitleLine" -> title_similarity
+func MigrateJunctionsConnectionPhase(ctx context.Context, oldDB, newDB *sql.DB, dryRun bool) error {
+	log.Printf("=== Starting migration phase=\"junctions-connection\" dryRun=%v ===", dryRun)
+
+	log.Printf("--- Loading connection type ID map from id_map ---")
+	connTypeIDMap, err := loadIDMap16to16(ctx, newDB, "connection_type")
+	if err != nil {
+		return fmt.Errorf("load connection_type ID map: %w", err)
+	}
+
+	if err := migrateTitleConnection(ctx, oldDB, newDB, connTypeIDMap, dryRun); err != nil {
+		return fmt.Errorf("migrateTitleConnection: %w", err)
+	}
+
+	if err := migrateTitleSimilarity(ctx, oldDB, newDB, dryRun); err != nil {
+		return fmt.Errorf("migrateTitleSimilarity: %w", err)
+	}
+
+	log.Printf("=== Migration phase=\"junctions-connection\" completed successfully ===")
+	return nil
+}
+
+// ConnectionTitleLine -> title_connection
+//
+// Connections are directional ("follows" vs "followed by"), so rows are copied as-is.
+// Both ends must exist in the new title table.
+func migrateTitleConnection(
+	ctx context.Context,
+	oldDB, newDB *sql.DB,
+	connTypeIDMap map[int16]int16,
+	dryRun bool,
+) error {
+	var total int64
+	if err := oldDB.QueryRowContext(ctx, `SELECT COUNT(*) FROM "Lines"."ConnectionTitleLine"`).Scan(&total); err != nil {
+		return fmt.Errorf("count ConnectionTitleLine: %w", err)
+	}
+	log.Printf("migrateTitleConnection: %d rows in Lines.\"ConnectionTitleLine\"", total)
+
+	if dryRun {
+		log.Printf("migrateTitleConnection [DRY-RUN]: would process %d rows", total)
+		return nil
+	}
+
+	titleIDs, err := loadNewTitleIDSet(ctx, newDB)
+	if err != nil {
+		return err
+	}
+
+	rows, err := oldDB.QueryContext(ctx, `
+        SELECT "TitleID", "ConnectionTitleID", "ConnectionType"
+        FROM "Lines"."ConnectionTitleLine"
+        ORDER BY "TitleID"
+    `)
+	if err != nil {
+		return fmt.Errorf("query ConnectionTitleLine: %w", err)
+	}
+	defer rows.Close()
+
+	w, err := newBatchWriter(ctx, newDB, "junctions-connection", "title_connection", []string{`
+        INSERT INTO title_connection (title_id, other_title_id, connection_type_id)
+        VALUES ($1, $2, $3)
+        ON CONFLICT (title_id, other_title_id, connection_type_id) DO NOTHING
+    `}, "title_id", "other_title_id", "connection_type_id")
+	if err != nil {
+		return err
+	}
+
+	var processed int64
+	var skippedTitle, skippedType int64
+	start := time.Now()
+
+	for rows.Next() {
+		var titleID, otherTitleID int64
+		var oldTypeID int16
+		if err := rows.Scan(&titleID, &otherTitleID, &oldTypeID); err != nil {
+			return fmt.Errorf("scan ConnectionTitleLine: %w", err)
+		}
+
+		_, okTitle := titleIDs[titleID]
+		_, okOther := titleIDs[otherTitleID]
+		if !okTitle || !okOther {
+			skippedTitle++
+			continue
+		}
+		newTypeID, ok := connTypeIDMap[oldTypeID]
+		if !ok {
+			skippedType++
+			continue
+		}
+
+		if err := w.add(ctx, titleID, titleID, otherTitleID, newTypeID); err != nil {
+			return err
+		}
+
+		processed++
+		if processed%junctionProgressEvery == 0 && total > 0 {
+			pct := float64(processed) * 100.0 / float64(total)
+			logProgress("title_connection", processed, skippedTitle+skippedType, start,
+				"migrateTitleConnection: inserted %d/%d rows (%.1f%%)", processed, total, pct)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate ConnectionTitleLine: %w", err)
+	}
+
+	if err := w.close(ctx); err != nil {
+		return err
+	}
+	logStepDone("title_connection", processed, skippedTitle+skippedType, start,
+		"--- Done title_connection: %d rows processed, %d skipped (no title=%d, no connection type mapping=%d) ---",
+		processed, skippedTitle+skippedType, skippedTitle, skippedType)
+	return nil
+}
+
+// SimilaritiesTitleLine -> title_similarity
+//
+// Similarity is symmetric, but the old table often stores both A->B and B->A.
+// The de-duplication pass runs on the OLD side: every pair is folded to
+// (LEAST, GREATEST), self-references are dropped, and only DISTINCT pairs are
+// streamed. title_similarity enforces title_id < similar_title_id.
+func migrateTitleSimilarity(ctx context.Context, oldDB, newDB *sql.DB, dryRun bool) error {
+	var total, selfRefs, distinctPairs int64
+	if err := oldDB.QueryRowContext(ctx, `
+        SELECT
+            COUNT(*),
+            COUNT(*) FILTER (WHERE "TitleID" = "SimilarTitleID"),
+            COUNT(DISTINCT (LEAST("TitleID", "SimilarTitleID"), GREATEST("TitleID", "SimilarTitleID")))
+                FILTER (WHERE "TitleID" <> "SimilarTitleID")
+        FROM "Lines"."SimilaritiesTitleLine"
+    `).Scan(&total, &selfRefs, &distinctPairs); err != nil {
+		return fmt.Errorf("count SimilaritiesTitleLine: %w", err)
+	}
+	mirrored := total - selfRefs - distinctPairs
+	log.Printf("migrateTitleSimilarity: %d rows in Lines.\"SimilaritiesTitleLine\" -> %d distinct pairs (%d mirrored A<->B duplicates, %d self-references dropped)",
+		total, distinctPairs, mirrored, selfRefs)
+
+	if dryRun {
+		log.Printf("migrateTitleSimilarity [DRY-RUN]: would process %d pairs", distinctPairs)
+		return nil
+	}
+
+	titleIDs, err := loadNewTitleIDSet(ctx, newDB)
+	if err != nil {
+		return err
+	}
+
+	rows, err := oldDB.QueryContext(ctx, `
+        SELECT DISTINCT
+            LEAST("TitleID", "SimilarTitleID")    AS a,
+            GREATEST("TitleID", "SimilarTitleID") AS b
+        FROM "Lines"."SimilaritiesTitleLine"
+        WHERE "TitleID" <> "SimilarTitleID"
+        ORDER BY a, b
+    `)
+	if err != nil {
+		return fmt.Errorf("query SimilaritiesTitleLine: %w", err)
+	}
+	defer rows.Close()
+
+	w, err := newBatchWriter(ctx, newDB, "junctions-connection", "title_similarity", []string{`
+        INSERT INTO title_similarity (title_id, similar_title_id)
+        VALUES ($1, $2)
+        ON CONFLICT (title_id, similar_title_id) DO NOTHING
+    `}, "title_id", "similar_title_id")
+	if err != nil {
+		return err
+	}
+
+	var processed int64
+	var skipped int64
+	start := time.Now()
+
+	for rows.Next() {
+		var a, b int64
+		if err := rows.Scan(&a, &b); err != nil {
+			return fmt.Errorf("scan SimilaritiesTitleLine: %w", err)
+		}
+
+		_, okA := titleIDs[a]
+		_, okB := titleIDs[b]
+		if !okA || !okB {
+			skipped++
+			continue
+		}
+
+		if err := w.add(ctx, a, a, b); err != nil {
+			return err
+		}
+
+		processed++
+		if processed%junctionProgressEvery == 0 && distinctPairs > 0 {
+			pct := float64(processed) * 100.0 / float64(distinctPairs)
+			logProgress("title_similarity", processed, skipped, start,
+				"migrateTitleSimilarity: inserted %d/%d pairs (%.1f%%)", processed, distinctPairs, pct)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate SimilaritiesTitleLine: %w", err)
+	}
+
+	if err := w.close(ctx); err != nil {
+		return err
+	}
+	logStepDone("title_similarity", processed, skipped, start,
+		"--- Done title_similarity: %d pairs processed, %d skipped (no title) ---", processed, skipped)
+	return nil
+}
@@ -0,0 +1,151 @@
+// cmd/migrate-old-db/phase_junctions_jobs.go
+package main
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// loadJobs for the title_* junctions, shared by the row and COPY paths (see
+// runJunctionJob). Old IDs are mapped while streaming and unmapped rows are
+// skipped, rows whose title or person is missing in the new DB are dropped.
+//
+// Every srcQuery takes a TitleID range: $1 <= "TitleID" < $2.
+
+// mappedPairScan reads (TitleID, old ref ID) and maps the ref ID through idMap.
+func mappedPairScan(source string, idMap map[int32]int16) func(rows *sql.Rows) ([]interface{}, bool, error) {
+	return func(rows *sql.Rows) ([]interface{}, bool, error) {
+		var titleID int64
+		var oldID int32
+		if err := rows.Scan(&titleID, &oldID); err != nil {
+			return nil, false, fmt.Errorf("scan %s: %w", source, err)
+		}
+		newID, ok := idMap[oldID]
+		if !ok {
+			return nil, false, nil
+		}
+		return []interface{}{titleID, newID}, true, nil
+	}
+}
+
+// CountryTitleLine -> title_country
+func titleCountryJob(countryIDMap map[int32]int16) loadJob {
+	return loadJob{
+		target:       "title_country",
+		columns:      []string{"title_id", "country_id"},
+		keyColumns:   []string{"title_id", "country_id"},
+		doNothing:    true,
+		requireTitle: true,
+		rangeSource:  `"Lines"."CountryTitleLine"`,
+		srcQuery: `
+			SELECT "TitleID", "CountryID"
+			FROM "Lines"."CountryTitleLine"
+			WHERE "TitleID" >= $1 AND "TitleID" < $2
+		`,
+		scan: mappedPairScan("CountryTitleLine", countryIDMap),
+	}
+}
+
+// LanguageTitleLine -> title_language
+//
+// is_original is left at its default (false); migrateTitleOriginalLanguage
+// sets it afterwards.
+func titleLanguageJob(langIDMap map[int32]int16) loadJob {
+	return loadJob{
+		target:       "title_language",
+		columns:      []string{"title_id", "language_id"},
+		keyColumns:   []string{"title_id", "language_id"},
+		doNothing:    true,
+		requireTitle: true,
+		rangeSource:  `"Lines"."LanguageTitleLine"`,
+		srcQuery: `
+			SELECT "TitleID", "LanguageID"
+			FROM "Lines"."LanguageTitleLine"
+			WHERE "TitleID" >= $1 AND "TitleID" < $2
+		`,
+		scan: mappedPairScan("LanguageTitleLine", langIDMap),
+	}
+}
+
+// GenreTitleLine -> title_genre
+func titleGenreJob(genreIDMap map[int32]int16) loadJob {
+	return loadJob{
+		target:       "title_genre",
+		columns:      []string{"title_id", "genre_id"},
+		keyColumns:   []string{"title_id", "genre_id"},
+		doNothing:    true,
+		requireTitle: true,
+		rangeSource:  `"Lines"."GenreTitleLine"`,
+		srcQuery: `
+			SELECT "TitleID", "GenreID"
+			FROM "Lines"."GenreTitleLine"
+			WHERE "TitleID" >= $1 AND "TitleID" < $2
+		`,
+		scan: mappedPairScan("GenreTitleLine", genreIDMap),
+	}
+}
+
+// CertificateTitleLine -> title_certificate
+func titleCertificateJob(countryIDMap, certIDMap map[int32]int16) loadJob {
+	return loadJob{
+		target:       "title_certificate",
+		columns:      []string{"title_id", "certificate_id", "country_id"},
+		keyColumns:   []string{"title_id", "certificate_id", "country_id"},
+		doNothing:    true,
+		requireTitle: true,
+		rangeSource:  `"Lines"."CertificateTitleLine"`,
+		srcQuery: `
+			SELECT "TitleID", "CertificateID", "CountryID"
+			FROM "Lines"."CertificateTitleLine"
+			WHERE "TitleID" >= $1 AND "TitleID" < $2
+		`,
+		scan: func(rows *sql.Rows) ([]interface{}, bool, error) {
+			var titleID int64
+			var oldCertID, oldCountryID int32
+			if err := rows.Scan(&titleID, &oldCertID, &oldCountryID); err != nil {
+				return nil, false, fmt.Errorf("scan CertificateTitleLine: %w", err)
+			}
+			newCertID, okCert := certIDMap[oldCertID]
+			newCountryID, okCountry := countryIDMap[oldCountryID]
+			if !okCert || !okCountry {
+				return nil, false, nil
+			}
+			return []interface{}{titleID, newCertID, newCountryID}, true, nil
+		},
+	}
+}
+
+// CastTitleLine -> title_cast; merged persons are resolved through merges.
+func titleCastJob(roleIDMap map[int16]int16, merges map[int64]int64) loadJob {
+	return loadJob{
+		target:        "title_cast",
+		columns:       []string{"title_id", "person_id", "role_type_id", "character_name", "billing_order"},
+		keyColumns:    []string{"title_id", "person_id", "role_type_id"},
+		requireTitle:  true,
+		requirePerson: true,
+		rangeSource:   `"Lines"."CastTitleLine"`,
+		srcQuery: `
+			SELECT "TitleID", "CastID", "CastType", "CastRole", "Sequence"
+			FROM "Lines"."CastTitleLine"
+			WHERE "TitleID" >= $1 AND "TitleID" < $2
+		`,
+		scan: func(rows *sql.Rows) ([]interface{}, bool, error) {
+			var (
+				titleID   int64
+				castID    int64
+				oldRoleID int16
+				castRole  sql.NullString
+				sequence  int16
+			)
+			if err := rows.Scan(&titleID, &castID, &oldRoleID, &castRole, &sequence); err != nil {
+				return nil, false, fmt.Errorf("scan CastTitleLine: %w", err)
+			}
+			newRoleID, ok := roleIDMap[oldRoleID]
+			if !ok {
+				return nil, false, nil
+			}
+			personID := resolvePerson(merges, castID)
+			return []interface{}{titleID, personID, newRoleID, nullStringOrNil(castRole), sequence}, true, nil
+		},
+	}
+}
@@ -0,0 +1,310 @@
+// cmd/migrate-old-db/phase_media_files.go
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// MigrateMediaFilesPhase runs the "media-files" phase:
+//
+//	Lines."FileTitleLine" + TitleTable.FolderPath/FolderName -> media_file
+func MigrateMediaFilesPhase(ctx context.Context, oldDB, newDB *sql.DB, dryRun bool) error {
+	start := time.Now()
+	log.Printf("=== Starting migration phase=\"media-files\" dryRun=%v checkFiles=%v ===", dryRun, *checkFiles)
+
+	prefixes, err := parsePathMap(*pathMap)
+	if err != nil {
+		return err
+	}
+	if !*checkFiles {
+		log.Printf("media-files: -check-files is off; no folders are checked and existing is_missing / last_checked_at values are kept")
+	}
+
+	log.Printf("--- Loading quality ID map from id_map ---")
+	qualityIDMap, err := loadIDMap32to16(ctx, newDB, "quality")
+	if err != nil {
+		return fmt.Errorf("load quality ID map: %w", err)
+	}
+
+	log.Printf("--- Loading display ID map from id_map ---")
+	displayIDMap, err := loadIDMap32to16(ctx, newDB, "display")
+	if err != nil {
+		return fmt.Errorf("load display ID map: %w", err)
+	}
+
+	log.Printf("--- Loading language ID map from id_map ---")
+	langIDMap, err := loadIDMap32to16(ctx, newDB, "language")
+	if err != nil {
+		return fmt.Errorf("load language ID map: %w", err)
+	}
+
+	if err := migrateMediaFiles(ctx, oldDB, newDB, qualityIDMap, displayIDMap, langIDMap, prefixes, dryRun); err != nil {
+		return fmt.Errorf("migrateMediaFiles: %w", err)
+	}
+
+	log.Printf("=== Migration phase=\"media-files\" completed successfully in %s ===", time.Since(start))
+	return nil
+}
+
+// FileTitleLine -> media_file
+//
+// The old DB has no per-file path, only the title folder, so file_path is the
+// title folder (FolderPath + FolderName). Unmapped quality / display / language
+// ids become NULL (the file still exists, we just lose that attribute).
+// With -check-files, each folder is stat'ed once (through -path-map, see
+// localPath) and missing ones get is_missing = true. Folders with no local
+// path are left unchecked. An unchecked row keeps the is_missing and
+// last_checked_at of the stored row, so a run without -check-files does not
+// undo an earlier check.
+func migrateMediaFiles(
+	ctx context.Context,
+	oldDB, newDB *sql.DB,
+	qualityIDMap, displayIDMap, langIDMap map[int32]int16,
+	prefixes []pathPrefix,
+	dryRun bool,
+) error {
+	var total int64
+	if err := oldDB.QueryRowContext(ctx, `SELECT COUNT(*) FROM "Lines"."FileTitleLine"`).Scan(&total); err != nil {
+		return fmt.Errorf("count FileTitleLine: %w", err)
+	}
+	log.Printf("migrateMediaFiles: %d rows in Lines.\"FileTitleLine\"", total)
+
+	if dryRun {
+		log.Printf("migrateMediaFiles [DRY-RUN]: would process %d rows", total)
+		return nil
+	}
+
+	titleIDs, err := loadNewTitleIDSet(ctx, newDB)
+	if err != nil {
+		return err
+	}
+
+	rows, err := oldDB.QueryContext(ctx, `
+        SELECT
+            f."TitleID",
+            f."QualityID",
+            f."DisplayID",
+            f."AudioLanguageID",
+            f."SubtitleLanguageID",
+            t."FolderPath",
+            t."FolderName"
+        FROM "Lines"."FileTitleLine" f
+        JOIN "Tables"."TitleTable" t ON t."TitleID" = f."TitleID"
+        ORDER BY f."TitleID"
+    `)
+	if err != nil {
+		return fmt.Errorf("query FileTitleLine join TitleTable: %w", err)
+	}
+	defer rows.Close()
+
+	w, err := newBatchWriter(ctx, newDB, "media-files", "media_file", []string{`
+        INSERT INTO media_file (
+            title_id, quality_id, display_id, file_path,
+            audio_language_id, subtitle_language_id,
+            is_missing, last_checked_at
+        )
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+        ON CONFLICT ON CONSTRAINT media_file_uq DO UPDATE
+        SET is_missing      = CASE WHEN EXCLUDED.last_checked_at IS NULL
+                                   THEN media_file.is_missing
+                                   ELSE EXCLUDED.is_missing END,
+            last_checked_at = COALESCE(EXCLUDED.last_checked_at, media_file.last_checked_at),
+            updated_at      = now()
+    `}, "title_id", "quality_id", "display_id", "file_path", "audio_language_id", "subtitle_language_id", "is_missing", "last_checked_at")
+	if err != nil {
+		return err
+	}
+
+	var (
+		processed     int64
+		skippedTitle  int64
+		missingFolder int64
+		noFolderPath  int64
+		unmappedAttrs int64
+		uncheckable   int64
+	)
+
+	// Several FileTitleLine rows share one title folder: stat each folder once.
+	var (
+		lastTitleID int64 = -1
+		lastMissing bool
+		lastChecked bool
+	)
+	start := time.Now()
+
+	for rows.Next() {
+		var (
+			titleID     int64
+			oldQuality  int32
+			oldDisplay  int32
+			oldAudio    int32
+			oldSubtitle int32
+			folderPath  sql.NullString
+			folderName  string
+		)
+		if err := rows.Scan(&titleID, &oldQuality, &oldDisplay, &oldAudio, &oldSubtitle, &folderPath, &folderName); err != nil {
+			return fmt.Errorf("scan FileTitleLine: %w", err)
+		}
+
+		if _, ok := titleIDs[titleID]; !ok {
+			skippedTitle++
+			continue
+		}
+
+		filePath := joinLegacyPath(folderPath.String, folderName)
+		if strings.TrimSpace(folderPath.String) == "" {
+			noFolderPath++
+		}
+
+		var checkedAt interface{}
+		if titleID != lastTitleID {
+			lastMissing, lastChecked = false, false
+			if *checkFiles {
+				if local, ok := localPath(filePath, prefixes); ok {
+					lastMissing, lastChecked = folderIsMissing(local), true
+				} else {
+					uncheckable++
+				}
+			}
+			lastTitleID = titleID
+			if lastMissing {
+				missingFolder++
+			}
+		}
+		if lastChecked {
+			checkedAt = time.Now()
+		}
+
+		qualityID := mappedIDOrNil(qualityIDMap, oldQuality)
+		displayID := mappedIDOrNil(displayIDMap, oldDisplay)
+		audioID := mappedIDOrNil(langIDMap, oldAudio)
+		subtitleID := mappedIDOrNil(langIDMap, oldSubtitle)
+		if qualityID == nil || displayID == nil || audioID == nil || subtitleID == nil {
+			unmappedAttrs++
+		}
+
+		if err := w.add(ctx, titleID,
+			titleID, qualityID, displayID, filePath,
+			audioID, subtitleID,
+			lastMissing, checkedAt,
+		); err != nil {
+			return err
+		}
+
+		processed++
+		if processed%junctionProgressEvery == 0 && total > 0 {
+			pct := float64(processed) * 100.0 / float64(total)
+			logProgress("media_file", processed, skippedTitle, start,
+				"migrateMediaFiles: inserted %d/%d rows (%.1f%%)", processed, total, pct)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate FileTitleLine: %w", err)
+	}
+
+	if err := w.close(ctx); err != nil {
+		return err
+	}
+	logStepDone("media_file", processed, skippedTitle, start,
+		"--- Done media_file: %d rows processed, %d skipped (no title), %d titles with missing folder, %d titles not checked (no -path-map prefix), %d rows without FolderPath, %d rows with an unmapped quality/display/language ---",
+		processed, skippedTitle, missingFolder, uncheckable, noFolderPath, unmappedAttrs)
+	return nil
+}
+
+// joinLegacyPath joins the old FolderPath and FolderName, keeping the
+// separator style of FolderPath (the LabVIEW apps stored Windows paths).
+// If FolderPath already ends in FolderName it is returned unchanged.
+func joinLegacyPath(folderPath, folderName string) string {
+	folderPath = strings.TrimSpace(folderPath)
+	folderName = strings.TrimSpace(folderName)
+	if folderPath == "" {
+		return folderName
+	}
+
+	sep := "/"
+	if strings.Contains(folderPath, `\`) {
+		sep = `\`
+	}
+	folderPath = strings.TrimRight(folderPath, `/\`)
+	if folderName == "" || strings.HasSuffix(folderPath, sep+folderName) {
+		return folderPath
+	}
+	return folderPath + sep + folderName
+}
+
+// pathPrefix is one -path-map pair: legacy folders under from are found
+// under to on this machine.
+type pathPrefix struct {
+	from, to string
+}
+
+// parsePathMap parses -path-map: "FROM=TO" pairs separated by ';'.
+func parsePathMap(s string) ([]pathPrefix, error) {
+	var out []pathPrefix
+	for _, pair := range strings.Split(s, ";") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		from, to, ok := strings.Cut(pair, "=")
+		from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+		if !ok || from == "" || to == "" {
+			return nil, fmt.Errorf("-path-map: %q is not FROM=TO", pair)
+		}
+		out = append(out, pathPrefix{from: slashPath(from), to: to})
+	}
+	return out, nil
+}
+
+// slashPath turns a legacy path into forward-slash form without a trailing
+// separator, so Windows and POSIX prefixes compare alike.
+func slashPath(p string) string {
+	return strings.TrimRight(strings.ReplaceAll(p, `\`, "/"), "/")
+}
+
+// localPath translates a legacy folder path for os.Stat. The first prefix it
+// starts with (ignoring case, as on Windows) is replaced by its local folder.
+// Without a matching prefix, a path with a drive letter or backslashes can't
+// be checked here and ok is false; other paths are used as they are.
+func localPath(legacy string, prefixes []pathPrefix) (path string, ok bool) {
+	p := slashPath(legacy)
+	for _, pre := range prefixes {
+		if len(p) < len(pre.from) || !strings.EqualFold(p[:len(pre.from)], pre.from) {
+			continue
+		}
+		rest := p[len(pre.from):]
+		if rest != "" && rest[0] != '/' {
+			continue
+		}
+		return filepath.Join(pre.to, filepath.FromSlash(rest)), true
+	}
+	if strings.Contains(legacy, `\`) || (len(p) >= 2 && p[1] == ':') {
+		return "", false
+	}
+	return legacy, true
+}
+
+// folderIsMissing reports whether path definitely does not exist. Other stat
+// errors (permissions, unreachable share) are not treated as missing.
+func folderIsMissing(path string) bool {
+	if path == "" {
+		return true
+	}
+	_, err := os.Stat(path)
+	return errors.Is(err, fs.ErrNotExist)
+}
+
+func mappedIDOrNil(m map[int32]int16, oldID int32) interface{} {
+	if newID, ok := m[oldID]; ok {
+		return newID
+	}
+	return nil
+}
@@ -0,0 +1,268 @@
+// cmd/migrate-old-db/phase_parental_guide.go
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// parentalGuideColumns are the TitleTable columns we unpivot, with the
+// parental_guide_category_ref name the refs phase seeds for each.
+var parentalGuideColumns = []struct {
+	column   string
+	category string
+}{
+	{"Nudity", "Sex & Nudity"},
+	{"Violence", "Violence & Gore"},
+	{"Profanity", "Profanity"},
+	{"AlcoholDrugSmoking", "Alcohol, Drugs & Smoking"},
+	{"Frightening", "Frightening & Intense Scenes"},
+}
+
+// MigrateParentalGuidePhase runs the "parental-guide" phase:
+//
+//	TitleTable.(Nudity|Violence|Profanity|AlcoholDrugSmoking|Frightening) -> title_parental_guide
+//
+// The TitleTable columns hold References."ParentGuideRef" ids, which is also
+// where the severity scale comes from (see loadParentGuideLevels).
+func MigrateParentalGuidePhase(ctx context.Context, oldDB, newDB *sql.DB, dryRun bool) error {
+	start := time.Now()
+	log.Printf("=== Starting migration phase=\"parental-guide\" dryRun=%v ===", dryRun)
+
+	log.Printf("--- Resolving parental_guide_category_ref ids for TitleTable columns ---")
+	categoryIDs, err := buildParentalGuideCategoryMap(ctx, newDB)
+	if err != nil {
+		return fmt.Errorf("buildParentalGuideCategoryMap: %w", err)
+	}
+
+	log.Printf("--- Loading the severity scale from References.\"ParentGuideRef\" ---")
+	levels, err := loadParentGuideLevels(ctx, oldDB)
+	if err != nil {
+		return fmt.Errorf("loadParentGuideLevels: %w", err)
+	}
+
+	if err := migrateTitleParentalGuide(ctx, oldDB, newDB, categoryIDs, levels, dryRun); err != nil {
+		return fmt.Errorf("migrateTitleParentalGuide: %w", err)
+	}
+
+	log.Printf("=== Migration phase=\"parental-guide\" completed successfully in %s ===", time.Since(start))
+	return nil
+}
+
+// buildParentalGuideCategoryMap returns TitleTable column name -> parental_guide_category_ref.id,
+// looked up by the exact category name. Columns whose category is missing are
+// left out and logged.
+func buildParentalGuideCategoryMap(ctx context.Context, newDB *sql.DB) (map[string]int16, error) {
+	rows, err := newDB.QueryContext(ctx, `SELECT id, name FROM parental_guide_category_ref`)
+	if err != nil {
+		return nil, fmt.Errorf("select new parental_guide_category_ref: %w", err)
+	}
+	defer rows.Close()
+
+	byName := make(map[string]int16)
+	for rows.Next() {
+		var id int16
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, fmt.Errorf("scan new parental_guide_category_ref: %w", err)
+		}
+		byName[name] = id
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate new parental_guide_category_ref: %w", err)
+	}
+
+	result := make(map[string]int16, len(parentalGuideColumns))
+	for _, col := range parentalGuideColumns {
+		id, ok := byName[col.category]
+		if !ok {
+			log.Printf("WARN: buildParentalGuideCategoryMap: no parental_guide_category_ref named %q (run refs); TitleTable.%q will be skipped",
+				col.category, col.column)
+			continue
+		}
+		result[col.column] = id
+		log.Printf("buildParentalGuideCategoryMap: TitleTable.%q -> category id=%d name=%q", col.column, id, col.category)
+	}
+
+	log.Printf("buildParentalGuideCategoryMap: mapped %d/%d columns", len(result), len(parentalGuideColumns))
+	return result, nil
+}
+
+// parentGuideSeverity maps ParentGuideRef.ParentGuideDescription to
+// title_parental_guide.severity.
+var parentGuideSeverity = map[string]int16{
+	"none":     0,
+	"mild":     1,
+	"moderate": 2,
+	"severe":   3,
+}
+
+// parentGuideLevel is one References."ParentGuideRef" row as stored in
+// title_parental_guide.
+type parentGuideLevel struct {
+	severity    int16
+	description string
+}
+
+// loadParentGuideLevels returns ParentGuideID -> level. The severity comes
+// from the description through parentGuideSeverity (case and surrounding
+// blanks ignored); the description itself is kept as is. A description not in
+// the table fails the load, as its severity would be a guess.
+func loadParentGuideLevels(ctx context.Context, oldDB *sql.DB) (map[int64]parentGuideLevel, error) {
+	rows, err := oldDB.QueryContext(ctx, `
+		SELECT "ParentGuideID", "ParentGuideDescription"
+		FROM "References"."ParentGuideRef"
+		ORDER BY "ParentGuideID"
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("query ParentGuideRef: %w", err)
+	}
+	defer rows.Close()
+
+	levels := make(map[int64]parentGuideLevel)
+	for rows.Next() {
+		var id int64
+		var description string
+		if err := rows.Scan(&id, &description); err != nil {
+			return nil, fmt.Errorf("scan ParentGuideRef row: %w", err)
+		}
+		severity, ok := parentGuideSeverity[strings.ToLower(strings.TrimSpace(description))]
+		if !ok {
+			return nil, fmt.Errorf("ParentGuideRef ParentGuideID=%d: unknown ParentGuideDescription %q (want None, Mild, Moderate or Severe)", id, description)
+		}
+		level := parentGuideLevel{severity: severity, description: description}
+		levels[id] = level
+		log.Printf("loadParentGuideLevels: ParentGuideID=%d %q -> severity %d", id, description, level.severity)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate ParentGuideRef: %w", err)
+	}
+	if len(levels) == 0 {
+		return nil, fmt.Errorf("References.\"ParentGuideRef\" is empty")
+	}
+	return levels, nil
+}
+
+// TitleTable parental guide columns -> title_parental_guide (one row per title and category)
+func migrateTitleParentalGuide(
+	ctx context.Context,
+	oldDB, newDB *sql.DB,
+	categoryIDs map[string]int16,
+	levels map[int64]parentGuideLevel,
+	dryRun bool,
+) error {
+	var total int64
+	if err := oldDB.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM "Tables"."TitleTable"
+		WHERE COALESCE("Nudity", "Violence", "Profanity", "AlcoholDrugSmoking", "Frightening") IS NOT NULL
+	`).Scan(&total); err != nil {
+		return fmt.Errorf("count TitleTable parental guide rows: %w", err)
+	}
+	log.Printf("migrateTitleParentalGuide: %d TitleTable rows have at least one parental guide value", total)
+
+	if dryRun {
+		log.Printf("migrateTitleParentalGuide [DRY-RUN]: would unpivot %d titles into title_parental_guide", total)
+		return nil
+	}
+
+	titleIDs, err := loadNewTitleIDSet(ctx, newDB)
+	if err != nil {
+		return err
+	}
+
+	rows, err := oldDB.QueryContext(ctx, `
+		SELECT "TitleID", "Nudity", "Violence", "Profanity", "AlcoholDrugSmoking", "Frightening"
+		FROM "Tables"."TitleTable"
+		WHERE COALESCE("Nudity", "Violence", "Profanity", "AlcoholDrugSmoking", "Frightening") IS NOT NULL
+		ORDER BY "TitleID"
+	`)
+	if err != nil {
+		return fmt.Errorf("query TitleTable parental guide: %w", err)
+	}
+	defer rows.Close()
+
+	w, err := newBatchWriter(ctx, newDB, "parental-guide", "title_parental_guide", []string{`
+		INSERT INTO title_parental_guide (title_id, category_id, severity, description)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (title_id, category_id) DO UPDATE
+		SET severity    = EXCLUDED.severity,
+		    description = EXCLUDED.description
+	`}, "title_id", "category_id", "severity", "description")
+	if err != nil {
+		return err
+	}
+
+	var (
+		titles          int64
+		inserted        int64
+		skippedTitle    int64
+		skippedCategory int64
+	)
+	start := time.Now()
+
+	for rows.Next() {
+		var titleID int64
+		values := make([]sql.NullInt64, len(parentalGuideColumns))
+		dest := []interface{}{&titleID}
+		for i := range values {
+			dest = append(dest, &values[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return fmt.Errorf("scan TitleTable parental guide row: %w", err)
+		}
+
+		titles++
+		if _, ok := titleIDs[titleID]; !ok {
+			skippedTitle++
+			continue
+		}
+
+		for i, col := range parentalGuideColumns {
+			v := values[i]
+			if !v.Valid {
+				continue
+			}
+			categoryID, ok := categoryIDs[col.column]
+			if !ok {
+				skippedCategory++
+				continue
+			}
+			level, ok := levels[v.Int64]
+			if !ok {
+				// Not a ParentGuideRef id: keep the raw value in migration_reject.
+				row := batchRow{key: titleID, args: []interface{}{titleID, categoryID, v.Int64, nil}}
+				if err := w.reject(ctx, row, fmt.Errorf("TitleTable.%q = %d is not a ParentGuideRef id", col.column, v.Int64)); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if err := w.add(ctx, titleID, titleID, categoryID, level.severity, level.description); err != nil {
+				return err
+			}
+			inserted++
+		}
+
+		if titles%junctionProgressEvery == 0 && total > 0 {
+			pct := float64(titles) * 100.0 / float64(total)
+			logProgress("title_parental_guide", inserted, skippedCategory, start,
+				"migrateTitleParentalGuide: processed %d/%d titles (%.1f%%), %d rows inserted", titles, total, pct, inserted)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate TitleTable parental guide: %w", err)
+	}
+
+	if err := w.close(ctx); err != nil {
+		return err
+	}
+	logStepDone("title_parental_guide", inserted, skippedCategory, start,
+		"--- Done title_parental_guide: %d titles processed, %d rows inserted/updated, skipped: %d titles (no title), %d values (no category), %d values rejected (not in ParentGuideRef) ---",
+		titles, inserted, skippedTitle, skippedCategory, w.rejected)
+	return nil
+}
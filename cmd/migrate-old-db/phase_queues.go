@@ -0,0 +1,222 @@
+// cmd/migrate-old-db/phase_queues.go
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+// MigrateQueuesPhase runs the "queues" phase:
+//
+//	Tables."RequestedTitles" -> requested_title
+//	Tables."NotDownloaded"   -> not_downloaded_title
+//	Tables."ToBeUpdated"     -> title_refresh_queue
+//
+// The old queues only store TitleID. title_name is resolved through
+// TitleTable.TitleName; imdb_id comes from the migrated title row (TitleTable
+// has no IMDb id column), so it is only filled once title.imdb_id is known.
+//
+// requested_title and not_downloaded_title keep the old TitleID in
+// old_title_id, which is what a re-run upserts on. A title that is not in the
+// new DB is still queued, with title_id NULL and its old name; once the title
+// exists, the next run fills in its title_id (or drops the row if another row
+// already queues that title). title_refresh_queue needs a title, so those
+// rows are only counted.
+func MigrateQueuesPhase(ctx context.Context, oldDB, newDB *sql.DB, dryRun bool) error {
+	log.Printf("=== Starting migration phase=\"queues\" dryRun=%v ===", dryRun)
+
+	steps := []struct {
+		name        string
+		srcTable    string
+		keepNoTitle bool // insertSQL takes $2 = TitleTable.TitleName
+		insertSQL   string
+	}{
+		{
+			name:        "requested_title",
+			srcTable:    "RequestedTitles",
+			keepNoTitle: true,
+			insertSQL: `
+				INSERT INTO requested_title (old_title_id, title_id, imdb_id, title_name, notes)
+				SELECT $1, t.id, t.imdb_id, $2, 'migrated from Tables."RequestedTitles"'
+				FROM (VALUES (1)) AS one
+				LEFT JOIN title t ON t.id = $1
+				WHERE NOT EXISTS (
+					SELECT 1 FROM requested_title q
+					WHERE q.title_id = $1 AND q.old_title_id IS DISTINCT FROM $1
+				)
+				ON CONFLICT (old_title_id) DO UPDATE
+				SET title_id   = EXCLUDED.title_id,
+				    imdb_id    = EXCLUDED.imdb_id,
+				    title_name = EXCLUDED.title_name
+			`,
+		},
+		{
+			name:        "not_downloaded_title",
+			srcTable:    "NotDownloaded",
+			keepNoTitle: true,
+			insertSQL: `
+				INSERT INTO not_downloaded_title (old_title_id, title_id, imdb_id, title_name, reason)
+				SELECT $1, t.id, t.imdb_id, $2, 'migrated from Tables."NotDownloaded"'
+				FROM (VALUES (1)) AS one
+				LEFT JOIN title t ON t.id = $1
+				WHERE NOT EXISTS (
+					SELECT 1 FROM not_downloaded_title q
+					WHERE q.title_id = $1 AND q.old_title_id IS DISTINCT FROM $1
+				)
+				ON CONFLICT (old_title_id) DO UPDATE
+				SET title_id   = EXCLUDED.title_id,
+				    imdb_id    = EXCLUDED.imdb_id,
+				    title_name = EXCLUDED.title_name
+			`,
+		},
+		{
+			name:     "title_refresh_queue",
+			srcTable: "ToBeUpdated",
+			insertSQL: `
+				INSERT INTO title_refresh_queue (title_id, reason)
+				SELECT t.id, 'migrated from Tables."ToBeUpdated"'
+				FROM title t
+				WHERE t.id = $1
+				ON CONFLICT (title_id) DO NOTHING
+			`,
+		},
+	}
+
+	var titleIDs map[int64]struct{}
+	if !dryRun {
+		var err error
+		if titleIDs, err = loadNewTitleIDSet(ctx, newDB); err != nil {
+			return err
+		}
+	}
+
+	start := time.Now()
+	for _, step := range steps {
+		log.Printf("--- Migrating %s (Tables.%q) ---", step.name, step.srcTable)
+		stepStart := time.Now()
+
+		if err := migrateTitleQueue(ctx, oldDB, newDB, step.srcTable, step.name, step.insertSQL, step.keepNoTitle, titleIDs, dryRun); err != nil {
+			return fmt.Errorf("migration step %s failed: %w", step.name, err)
+		}
+
+		log.Printf("--- Done %s in %s ---", step.name, time.Since(stepStart))
+	}
+
+	log.Printf("=== Migration phase=\"queues\" completed successfully in %s ===", time.Since(start))
+	return nil
+}
+
+// migrateTitleQueue copies one TitleID-only queue table. insertSQL receives
+// $1 = TitleID. With keepNoTitle it also gets $2 = TitleTable.TitleName, must
+// upsert on old_title_id and queue a title that is not in the new DB with
+// title_id NULL; afterwards the title_id NULL rows whose title is queued by
+// another row are deleted. Otherwise insertSQL must insert nothing for such a
+// title; those rows are counted as missing.
+func migrateTitleQueue(
+	ctx context.Context,
+	oldDB, newDB *sql.DB,
+	srcTable, dstTable, insertSQL string,
+	keepNoTitle bool,
+	titleIDs map[int64]struct{},
+	dryRun bool,
+) error {
+	start := time.Now()
+	rows, err := oldDB.QueryContext(ctx, fmt.Sprintf(`
+		SELECT q."TitleID", t."TitleName"
+		FROM "Tables".%q q
+		LEFT JOIN "Tables"."TitleTable" t ON t."TitleID" = q."TitleID"
+		ORDER BY q."TitleID"
+	`, srcTable))
+	if err != nil {
+		return fmt.Errorf("query %s: %w", srcTable, err)
+	}
+	defer rows.Close()
+
+	type queueRow struct {
+		titleID   int64
+		titleName sql.NullString
+	}
+
+	var (
+		allRows  []queueRow
+		orphaned int64
+	)
+	for rows.Next() {
+		var r queueRow
+		if err := rows.Scan(&r.titleID, &r.titleName); err != nil {
+			return fmt.Errorf("scan %s row: %w", srcTable, err)
+		}
+		if !r.titleName.Valid {
+			orphaned++
+			if orphaned <= 20 {
+				log.Printf("WARN: migrateTitleQueue: Tables.%q TitleID=%d has no TitleTable row", srcTable, r.titleID)
+			}
+		}
+		allRows = append(allRows, r)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate %s: %w", srcTable, err)
+	}
+
+	log.Printf("migrateTitleQueue: read %d rows from Tables.%q (%d without a TitleTable row)",
+		len(allRows), srcTable, orphaned)
+
+	if dryRun {
+		log.Printf("migrateTitleQueue [DRY-RUN]: would insert/update up to %d %s rows", len(allRows), dstTable)
+		return nil
+	}
+
+	columns := []string{"title_id"}
+	if keepNoTitle {
+		columns = append(columns, "title_name")
+	}
+	w, err := newBatchWriter(ctx, newDB, "queues", dstTable, []string{insertSQL}, columns...)
+	if err != nil {
+		return err
+	}
+	var missing int64
+	for _, r := range allRows {
+		if _, ok := titleIDs[r.titleID]; !ok {
+			missing++
+		}
+		args := []interface{}{r.titleID}
+		if keepNoTitle {
+			args = append(args, nullStringOrNil(r.titleName))
+		}
+		if err := w.add(ctx, r.titleID, args...); err != nil {
+			return err
+		}
+	}
+	if err := w.close(ctx); err != nil {
+		return err
+	}
+	written := w.affected
+	skipped := w.written - w.affected
+
+	if keepNoTitle {
+		res, err := newDB.ExecContext(ctx, fmt.Sprintf(`
+			DELETE FROM %[1]s p
+			WHERE p.title_id IS NULL
+			  AND EXISTS (SELECT 1 FROM %[1]s q WHERE q.title_id = p.old_title_id)
+		`, dstTable))
+		if err != nil {
+			return fmt.Errorf("delete %s rows queued twice: %w", dstTable, err)
+		}
+		deleted, _ := res.RowsAffected()
+
+		logStepDone(dstTable, written, skipped, start,
+			"migrateTitleQueue: %s: %d rows inserted/updated (%d with no title in the new DB, queued with title_id NULL), %d skipped (title already queued by another row), %d title_id NULL rows deleted (title now queued by another row)",
+			dstTable, written, missing, skipped, deleted)
+		return nil
+	}
+	logStepDone(dstTable, written, skipped, start,
+		"migrateTitleQueue: %s: %d rows inserted/updated, %d skipped (%d title not in new DB, %d already queued)",
+		dstTable, written, skipped, missing, skipped-missing)
+	if missing > 0 {
+		log.Printf("WARN: migrateTitleQueue: %s: %d Tables.%q rows dropped, their title is not in the new DB", dstTable, missing, srcTable)
+	}
+	return nil
+}
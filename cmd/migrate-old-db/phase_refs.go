@@ -16,11 +16,12 @@ func MigrateRefsPhase(ctx context.Context, oldDB, newDB *sql.DB, dryRun bool) er
 
 	steps := []struct {
 		name string
-		fn   func(context.Context, *sql.DB, *sql.DB, bool) error
+		fn   func(context.Context, *sql.DB, *sql.DB, bool) (int64, error)
 	}{
 		{"country_ref", migrateCountryRef},
 		{"language_ref", migrateLanguageRef},
 		{"genre_ref", migrateGenreRef},
+		{"category_ref", migrateCategoryRef},
 		{"certificate_ref", migrateCertificateRef},
 		{"title_type_ref", migrateTitleTypeRef},
 		{"connection_type_ref", migrateConnectionTypeRef},
@@ -31,18 +32,19 @@ func MigrateRefsPhase(ctx context.Context, oldDB, newDB *sql.DB, dryRun bool) er
 		{"award_event_ref", migrateAwardEventRef},
 		{"award_nomination_type_ref", migrateAwardNominationTypeRef},
 		{"certificate_country", migrateCertificateCountry},
+		{"id_map", migrateRefIDMap},
 	}
 
 	start := time.Now()
 	for _, step := range steps {
 		log.Printf("--- Migrating %s ---", step.name)
 		stepStart := time.Now()
-
-		if err := step.fn(ctx, oldDB, newDB, dryRun); err != nil {
+		written, err := step.fn(ctx, oldDB, newDB, dryRun)
+		if err != nil {
 			return fmt.Errorf("migration step %s failed: %w", step.name, err)
 		}
 
-		log.Printf("--- Done %s in %s ---", step.name, time.Since(stepStart))
+		logStepDone(step.name, written, 0, stepStart, "--- Done %s in %s ---", step.name, time.Since(stepStart))
 	}
 
 	log.Printf("=== All reference migrations completed in %s [%s] ===",
@@ -58,17 +60,17 @@ func modeLabel(dryRun bool) string {
 	return "LIVE"
 }
 
-// migrateCountryRef migrates Countries."CountryRef" -> country_ref.
-func migrateCountryRef(ctx context.Context, oldDB, newDB *sql.DB, dryRun bool) error {
+// migrateCountryRef migrates References."CountryRef" -> country_ref.
+func migrateCountryRef(ctx context.Context, oldDB, newDB *sql.DB, dryRun bool) (int64, error) {
 	const srcQuery = `
 		SELECT "CountryID", "CountryName", "CountryCode"
-		FROM "Countries"."CountryRef"
+		FROM "References"."CountryRef"
 		ORDER BY "CountryID"
 	`
 
 	rows, err := oldDB.QueryContext(ctx, srcQuery)
 	if err != nil {
-		return fmt.Errorf("query CountryRef: %w", err)
+		return 0, fmt.Errorf("query CountryRef: %w", err)
 	}
 	defer rows.Close()
 
@@ -82,15 +84,15 @@ func migrateCountryRef(ctx context.Context, oldDB, newDB *sql.DB, dryRun bool) e
 	for rows.Next() {
 		var r countryRow
 		if err := rows.Scan(&r.id, &r.name, &r.code); err != nil {
-			return fmt.Errorf("scan CountryRef row: %w", err)
+			return 0, fmt.Errorf("scan CountryRef row: %w", err)
 		}
 		allRows = append(allRows, r)
 	}
 	if err := rows.Err(); err != nil {
-		return fmt.Errorf("iterate CountryRef: %w", err)
+		return 0, fmt.Errorf("iterate CountryRef: %w", err)
 	}
 
-	log.Printf("migrateCountryRef: read %d rows from Countries.\"CountryRef\"", len(allRows))
+	log.Printf("migrateCountryRef: read %d rows from References.\"CountryRef\"", len(allRows))
 
 	if dryRun {
 		// Just log some stats and return.
@@ -106,30 +108,23 @@ func migrateCountryRef(ctx context.Context, oldDB, newDB *sql.DB, dryRun bool) e
 			}
 		}
 		log.Printf("migrateCountryRef [DRY-RUN]: %d rows total, %d rows have non-ISO-like codes", len(allRows), nonISO)
-		return nil
+		return 0, nil
 	}
 
 	// Insert into new.country_ref
-	tx, err := newDB.BeginTx(ctx, nil)
-	if err != nil {
-		return fmt.Errorf("begin tx country_ref: %w", err)
-	}
-	defer tx.Rollback()
-
 	const insertSQL = `
-		INSERT INTO country_ref (id, name, iso2, iso3)
+		INSERT INTO country_ref (id, name, iso2_code, iso3_code)
 		VALUES ($1, $2, $3, $4)
 		ON CONFLICT (id) DO UPDATE
-		SET name = EXCLUDED.name,
-		    iso2 = EXCLUDED.iso2,
-		    iso3 = EXCLUDED.iso3
+		SET name      = EXCLUDED.name,
+		    iso2_code = EXCLUDED.iso2_code,
+		    iso3_code = EXCLUDED.iso3_code
 	`
 
-	stmt, err := tx.PrepareContext(ctx, insertSQL)
+	w, err := newBatchWriter(ctx, newDB, "refs", "country_ref", []string{insertSQL}, "id", "name", "iso2_code", "iso3_code")
 	if err != nil {
-		return fmt.Errorf("prepare insert country_ref: %w", err)
+		return 0, err
 	}
-	defer stmt.Close()
 
 	var nonISO int
 	for _, r := range allRows {
@@ -148,33 +143,40 @@ func migrateCountryRef(ctx context.Context, oldDB, newDB *sql.DB, dryRun bool) e
 			}
 		}
 
-		if _, err := stmt.ExecContext(ctx, r.id, r.name, iso2, iso3); err != nil {
-			return fmt.Errorf("insert country_ref id=%d: %w", r.id, err)
+		if err := w.add(ctx, r.id, r.id, r.name, iso2, iso3); err != nil {
+			return 0, err
 		}
 	}
 
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("commit country_ref: %w", err)
+	if err := w.close(ctx); err != nil {
+		return 0, err
 	}
 
 	if nonISO > 0 {
 		log.Printf("migrateCountryRef: %d rows had non-ISO codes; inserted with NULL iso2/iso3", nonISO)
 	}
 
-	return nil
+	return w.written, nil
 }
 
-// migrateLanguageRef migrates Languages."LanguageRef" -> language_ref.
-func migrateLanguageRef(ctx context.Context, oldDB, newDB *sql.DB, dryRun bool) error {
+// The LanguageRef code the old apps use for "no language", and the ISO 639-2
+// code it is stored as.
+const (
+	undefinedLanguageCode = "Undefined"
+	undeterminedISOCode   = "und"
+)
+
+// migrateLanguageRef migrates References."LanguageRef" -> language_ref.
+func migrateLanguageRef(ctx context.Context, oldDB, newDB *sql.DB, dryRun bool) (int64, error) {
 	const srcQuery = `
 		SELECT "LanguageID", "LanguageName", "LanguageCode"
-		FROM "Languages"."LanguageRef"
+		FROM "References"."LanguageRef"
 		ORDER BY "LanguageID"
 	`
 
 	rows, err := oldDB.QueryContext(ctx, srcQuery)
 	if err != nil {
-		return fmt.Errorf("query LanguageRef: %w", err)
+		return 0, fmt.Errorf("query LanguageRef: %w", err)
 	}
 	defer rows.Close()
 
@@ -188,68 +190,69 @@ func migrateLanguageRef(ctx context.Context, oldDB, newDB *sql.DB, dryRun bool)
 	for rows.Next() {
 		var r langRow
 		if err := rows.Scan(&r.id, &r.name, &r.code); err != nil {
-			return fmt.Errorf("scan LanguageRef row: %w", err)
+			return 0, fmt.Errorf("scan LanguageRef row: %w", err)
 		}
 		allRows = append(allRows, r)
 	}
 	if err := rows.Err(); err != nil {
-		return fmt.Errorf("iterate LanguageRef: %w", err)
+		return 0, fmt.Errorf("iterate LanguageRef: %w", err)
 	}
 
-	log.Printf("migrateLanguageRef: read %d rows from Languages.\"LanguageRef\"", len(allRows))
+	log.Printf("migrateLanguageRef: read %d rows from References.\"LanguageRef\"", len(allRows))
 
 	if dryRun {
-		return nil
+		return 0, nil
 	}
 
-	tx, err := newDB.BeginTx(ctx, nil)
-	if err != nil {
-		return fmt.Errorf("begin tx language_ref: %w", err)
-	}
-	defer tx.Rollback()
-
 	const insertSQL = `
-		INSERT INTO language_ref (id, name, code)
+		INSERT INTO language_ref (id, name, iso_code)
 		VALUES ($1, $2, $3)
 		ON CONFLICT (id) DO UPDATE
-		SET name = EXCLUDED.name,
-		    code = EXCLUDED.code
+		SET name     = EXCLUDED.name,
+		    iso_code = EXCLUDED.iso_code
 	`
 
-	stmt, err := tx.PrepareContext(ctx, insertSQL)
+	w, err := newBatchWriter(ctx, newDB, "refs", "language_ref", []string{insertSQL}, "id", "name", "iso_code")
 	if err != nil {
-		return fmt.Errorf("prepare insert language_ref: %w", err)
+		return 0, err
 	}
-	defer stmt.Close()
 
 	for _, r := range allRows {
+		// iso_code is NOT NULL: a row without a code goes to migration_reject.
+		// The old placeholder code "Undefined" becomes ISO 639-2 "und"
+		// (undetermined) rather than being copied as a code.
 		var code sql.NullString
-		if trimmed := strings.TrimSpace(r.code.String); trimmed != "" && trimmed != "Undefined" {
+		if trimmed := strings.TrimSpace(r.code.String); trimmed != "" {
+			if strings.EqualFold(trimmed, undefinedLanguageCode) {
+				log.Printf("migrateLanguageRef: LanguageID=%d name=%q has code %q; storing iso_code %q",
+					r.id, r.name, trimmed, undeterminedISOCode)
+				trimmed = undeterminedISOCode
+			}
 			code = sql.NullString{String: trimmed, Valid: true}
 		}
-		if _, err := stmt.ExecContext(ctx, r.id, r.name, code); err != nil {
-			return fmt.Errorf("insert language_ref id=%d: %w", r.id, err)
+		if err := w.add(ctx, r.id, r.id, r.name, code); err != nil {
+			return 0, err
 		}
 	}
 
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("commit language_ref: %w", err)
+	if err := w.close(ctx); err != nil {
+		return 0, err
 	}
 
-	return nil
+	return w.written, nil
 }
 
-// migrateGenreRef migrates Genres."GenreRef" -> genre_ref.
-func migrateGenreRef(ctx context.Context, oldDB, newDB *sql.DB, dryRun bool) error {
+// migrateGenreRef migrates References."GenreRef" -> genre_ref.
+func migrateGenreRef(ctx context.Context, oldDB, newDB *sql.DB, dryRun bool) (int64, error) {
 	const srcQuery = `
 		SELECT "GenreID", "GenreName"
-		FROM "Genres"."GenreRef"
+		FROM "References"."GenreRef"
 		ORDER BY "GenreID"
 	`
 
 	rows, err := oldDB.QueryContext(ctx, srcQuery)
 	if err != nil {
-		return fmt.Errorf("query GenreRef: %w", err)
+		return 0, fmt.Errorf("query GenreRef: %w", err)
 	}
 	defer rows.Close()
 
@@ -262,25 +265,19 @@ func migrateGenreRef(ctx context.Context, oldDB, newDB *sql.DB, dryRun bool) err
 	for rows.Next() {
 		var r genreRow
 		if err := rows.Scan(&r.id, &r.name); err != nil {
-			return fmt.Errorf("scan GenreRef row: %w", err)
+			return 0, fmt.Errorf("scan GenreRef row: %w", err)
 		}
 		allRows = append(allRows, r)
 	}
 	if err := rows.Err(); err != nil {
-		return fmt.Errorf("iterate GenreRef: %w", err)
+		return 0, fmt.Errorf("iterate GenreRef: %w", err)
 	}
 
-	log.Printf("migrateGenreRef: read %d rows from Genres.\"GenreRef\"", len(allRows))
+	log.Printf("migrateGenreRef: read %d rows from References.\"GenreRef\"", len(allRows))
 
 	if dryRun {
-		return nil
-	}
-
-	tx, err := newDB.BeginTx(ctx, nil)
-	if err != nil {
-		return fmt.Errorf("begin tx genre_ref: %w", err)
+		return 0, nil
 	}
-	defer tx.Rollback()
 
 	const insertSQL = `
 		INSERT INTO genre_ref (id, name)
@@ -289,312 +286,318 @@ func migrateGenreRef(ctx context.Context, oldDB, newDB *sql.DB, dryRun bool) err
 		SET name = EXCLUDED.name
 	`
 
-	stmt, err := tx.PrepareContext(ctx, insertSQL)
+	w, err := newBatchWriter(ctx, newDB, "refs", "genre_ref", []string{insertSQL}, "id", "name")
 	if err != nil {
-		return fmt.Errorf("prepare insert genre_ref: %w", err)
+		return 0, err
 	}
-	defer stmt.Close()
 
 	for _, r := range allRows {
-		if _, err := stmt.ExecContext(ctx, r.id, r.name); err != nil {
-			return fmt.Errorf("insert genre_ref id=%d: %w", r.id, err)
+		if err := w.add(ctx, r.id, r.id, r.name); err != nil {
+			return 0, err
 		}
 	}
 
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("commit genre_ref: %w", err)
+	if err := w.close(ctx); err != nil {
+		return 0, err
 	}
 
-	return nil
+	return w.written, nil
 }
 
-// migrateCertificateRef migrates Certificates."CertificateRef" -> certificate_ref.
-func migrateCertificateRef(ctx context.Context, oldDB, newDB *sql.DB, dryRun bool) error {
+// migrateCategoryRef migrates References."CategoryRef" -> category_ref.
+func migrateCategoryRef(ctx context.Context, oldDB, newDB *sql.DB, dryRun bool) (int64, error) {
 	const srcQuery = `
-		SELECT "CertificateID", "CertificateName"
-		FROM "Certificates"."CertificateRef"
-		ORDER BY "CertificateID"
+		SELECT "CategoryID", "CategoryDecription"
+		FROM "References"."CategoryRef"
+		ORDER BY "CategoryID"
 	`
 
 	rows, err := oldDB.QueryContext(ctx, srcQuery)
 	if err != nil {
-		return fmt.Errorf("query CertificateRef: %w", err)
+		return 0, fmt.Errorf("query CategoryRef: %w", err)
 	}
 	defer rows.Close()
 
-	type certRow struct {
+	type categoryRow struct {
 		id   int64
 		name string
 	}
 
-	var allRows []certRow
+	var allRows []categoryRow
 	for rows.Next() {
-		var r certRow
+		var r categoryRow
 		if err := rows.Scan(&r.id, &r.name); err != nil {
-			return fmt.Errorf("scan CertificateRef row: %w", err)
+			return 0, fmt.Errorf("scan CategoryRef row: %w", err)
 		}
+		r.name = strings.TrimSpace(r.name)
 		allRows = append(allRows, r)
 	}
 	if err := rows.Err(); err != nil {
-		return fmt.Errorf("iterate CertificateRef: %w", err)
+		return 0, fmt.Errorf("iterate CategoryRef: %w", err)
 	}
 
-	log.Printf("migrateCertificateRef: read %d rows from Certificates.\"CertificateRef\"", len(allRows))
+	log.Printf("migrateCategoryRef: read %d rows from References.\"CategoryRef\"", len(allRows))
 
 	if dryRun {
-		return nil
-	}
-
-	tx, err := newDB.BeginTx(ctx, nil)
-	if err != nil {
-		return fmt.Errorf("begin tx certificate_ref: %w", err)
+		return 0, nil
 	}
-	defer tx.Rollback()
 
 	const insertSQL = `
-		INSERT INTO certificate_ref (id, name)
+		INSERT INTO category_ref (id, name)
 		VALUES ($1, $2)
 		ON CONFLICT (id) DO UPDATE
 		SET name = EXCLUDED.name
 	`
 
-	stmt, err := tx.PrepareContext(ctx, insertSQL)
+	w, err := newBatchWriter(ctx, newDB, "refs", "category_ref", []string{insertSQL}, "id", "name")
 	if err != nil {
-		return fmt.Errorf("prepare insert certificate_ref: %w", err)
+		return 0, err
 	}
-	defer stmt.Close()
 
 	for _, r := range allRows {
-		if _, err := stmt.ExecContext(ctx, r.id, r.name); err != nil {
-			return fmt.Errorf("insert certificate_ref id=%d: %w", r.id, err)
+		if err := w.add(ctx, r.id, r.id, r.name); err != nil {
+			return 0, err
 		}
 	}
 
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("commit certificate_ref: %w", err)
+	if err := w.close(ctx); err != nil {
+		return 0, err
 	}
 
-	return nil
+	return w.written, nil
 }
 
-// migrateTitleTypeRef migrates TitleTypes."TitleTypeRef" -> title_type_ref.
-func migrateTitleTypeRef(ctx context.Context, oldDB, newDB *sql.DB, dryRun bool) error {
+// migrateCertificateRef migrates References."CertificateRef" -> certificate_ref.
+func migrateCertificateRef(ctx context.Context, oldDB, newDB *sql.DB, dryRun bool) (int64, error) {
 	const srcQuery = `
-		SELECT "TitleTypeID", "TitleTypeName"
-		FROM "TitleTypes"."TitleTypeRef"
-		ORDER BY "TitleTypeID"
+		SELECT "CertificateID", "CertificateName"
+		FROM "References"."CertificateRef"
+		ORDER BY "CertificateID"
 	`
 
 	rows, err := oldDB.QueryContext(ctx, srcQuery)
 	if err != nil {
-		return fmt.Errorf("query TitleTypeRef: %w", err)
+		return 0, fmt.Errorf("query CertificateRef: %w", err)
 	}
 	defer rows.Close()
 
-	type ttRow struct {
+	type certRow struct {
 		id   int64
 		name string
 	}
 
-	var allRows []ttRow
+	var allRows []certRow
 	for rows.Next() {
-		var r ttRow
+		var r certRow
 		if err := rows.Scan(&r.id, &r.name); err != nil {
-			return fmt.Errorf("scan TitleTypeRef row: %w", err)
+			return 0, fmt.Errorf("scan CertificateRef row: %w", err)
 		}
 		allRows = append(allRows, r)
 	}
 	if err := rows.Err(); err != nil {
-		return fmt.Errorf("iterate TitleTypeRef: %w", err)
+		return 0, fmt.Errorf("iterate CertificateRef: %w", err)
 	}
 
-	log.Printf("migrateTitleTypeRef: read %d rows from TitleTypes.\"TitleTypeRef\"", len(allRows))
+	log.Printf("migrateCertificateRef: read %d rows from References.\"CertificateRef\"", len(allRows))
 
 	if dryRun {
-		return nil
-	}
-
-	tx, err := newDB.BeginTx(ctx, nil)
-	if err != nil {
-		return fmt.Errorf("begin tx title_type_ref: %w", err)
+		return 0, nil
 	}
-	defer tx.Rollback()
 
 	const insertSQL = `
-		INSERT INTO title_type_ref (id, name)
+		INSERT INTO certificate_ref (id, name)
 		VALUES ($1, $2)
 		ON CONFLICT (id) DO UPDATE
 		SET name = EXCLUDED.name
 	`
 
-	stmt, err := tx.PrepareContext(ctx, insertSQL)
+	w, err := newBatchWriter(ctx, newDB, "refs", "certificate_ref", []string{insertSQL}, "id", "name")
 	if err != nil {
-		return fmt.Errorf("prepare insert title_type_ref: %w", err)
+		return 0, err
 	}
-	defer stmt.Close()
 
 	for _, r := range allRows {
-		if _, err := stmt.ExecContext(ctx, r.id, r.name); err != nil {
-			return fmt.Errorf("insert title_type_ref id=%d: %w", r.id, err)
+		if err := w.add(ctx, r.id, r.id, r.name); err != nil {
+			return 0, err
 		}
 	}
 
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("commit title_type_ref: %w", err)
+	if err := w.close(ctx); err != nil {
+		return 0, err
 	}
 
-	return nil
+	return w.written, nil
 }
 
-// migrateConnectionTypeRef migrates Connections."ConnectionTypeRef" -> connection_type_ref.
-func migrateConnectionTypeRef(ctx context.Context, oldDB, newDB *sql.DB, dryRun bool) error {
+// migrateTitleTypeRef migrates References."TitleTypeRef" -> title_type_ref.
+func migrateTitleTypeRef(ctx context.Context, oldDB, newDB *sql.DB, dryRun bool) (int64, error) {
 	const srcQuery = `
-		SELECT "ConnectionTypeID", "ConnectionTypeName"
-		FROM "Connections"."ConnectionTypeRef"
-		ORDER BY "ConnectionTypeID"
+		SELECT "TypeID", "TypeName"
+		FROM "References"."TitleTypeRef"
+		ORDER BY "TypeID"
 	`
 
 	rows, err := oldDB.QueryContext(ctx, srcQuery)
 	if err != nil {
-		return fmt.Errorf("query ConnectionTypeRef: %w", err)
+		return 0, fmt.Errorf("query TitleTypeRef: %w", err)
 	}
 	defer rows.Close()
 
-	type connRow struct {
+	type ttRow struct {
 		id   int64
 		name string
 	}
 
-	var allRows []connRow
+	var allRows []ttRow
 	for rows.Next() {
-		var r connRow
+		var r ttRow
 		if err := rows.Scan(&r.id, &r.name); err != nil {
-			return fmt.Errorf("scan ConnectionTypeRef row: %w", err)
+			return 0, fmt.Errorf("scan TitleTypeRef row: %w", err)
 		}
 		allRows = append(allRows, r)
 	}
 	if err := rows.Err(); err != nil {
-		return fmt.Errorf("iterate ConnectionTypeRef: %w", err)
+		return 0, fmt.Errorf("iterate TitleTypeRef: %w", err)
 	}
 
-	log.Printf("migrateConnectionTypeRef: read %d rows from Connections.\"ConnectionTypeRef\"", len(allRows))
+	log.Printf("migrateTitleTypeRef: read %d rows from References.\"TitleTypeRef\"", len(allRows))
 
 	if dryRun {
-		return nil
-	}
-
-	tx, err := newDB.BeginTx(ctx, nil)
-	if err != nil {
-		return fmt.Errorf("begin tx connection_type_ref: %w", err)
+		return 0, nil
 	}
-	defer tx.Rollback()
 
 	const insertSQL = `
-		INSERT INTO connection_type_ref (id, name)
+		INSERT INTO title_type_ref (id, name)
 		VALUES ($1, $2)
 		ON CONFLICT (id) DO UPDATE
 		SET name = EXCLUDED.name
 	`
 
-	stmt, err := tx.PrepareContext(ctx, insertSQL)
+	w, err := newBatchWriter(ctx, newDB, "refs", "title_type_ref", []string{insertSQL}, "id", "name")
 	if err != nil {
-		return fmt.Errorf("prepare insert connection_type_ref: %w", err)
+		return 0, err
 	}
-	defer stmt.Close()
 
 	for _, r := range allRows {
-		if _, err := stmt.ExecContext(ctx, r.id, r.name); err != nil {
-			return fmt.Errorf("insert connection_type_ref id=%d: %w", r.id, err)
+		if err := w.add(ctx, r.id, r.id, r.name); err != nil {
+			return 0, err
 		}
 	}
 
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("commit connection_type_ref: %w", err)
+	if err := w.close(ctx); err != nil {
+		return 0, err
 	}
 
-	return nil
+	return w.written, nil
 }
 
-// migrateParentalGuideRef migrates ParentsGuide."ParentsGuideCategoryRef" -> parental_guide_category_ref.
-func migrateParentalGuideRef(ctx context.Context, oldDB, newDB *sql.DB, dryRun bool) error {
+// migrateConnectionTypeRef migrates References."ConnectionTypeRef" -> connection_type_ref.
+func migrateConnectionTypeRef(ctx context.Context, oldDB, newDB *sql.DB, dryRun bool) (int64, error) {
 	const srcQuery = `
-		SELECT "ParentsGuideCategoryID", "ParentsGuideCategoryName"
-		FROM "ParentsGuide"."ParentsGuideCategoryRef"
-		ORDER BY "ParentsGuideCategoryID"
+		SELECT "ConnectionTypeID", "ConnectionTypeDescription"
+		FROM "References"."ConnectionTypeRef"
+		ORDER BY "ConnectionTypeID"
 	`
 
 	rows, err := oldDB.QueryContext(ctx, srcQuery)
 	if err != nil {
-		return fmt.Errorf("query ParentsGuideCategoryRef: %w", err)
+		return 0, fmt.Errorf("query ConnectionTypeRef: %w", err)
 	}
 	defer rows.Close()
 
-	type pgRow struct {
+	type connRow struct {
 		id   int64
 		name string
 	}
 
-	var allRows []pgRow
+	var allRows []connRow
 	for rows.Next() {
-		var r pgRow
+		var r connRow
 		if err := rows.Scan(&r.id, &r.name); err != nil {
-			return fmt.Errorf("scan ParentsGuideCategoryRef row: %w", err)
+			return 0, fmt.Errorf("scan ConnectionTypeRef row: %w", err)
 		}
 		allRows = append(allRows, r)
 	}
 	if err := rows.Err(); err != nil {
-		return fmt.Errorf("iterate ParentsGuideCategoryRef: %w", err)
+		return 0, fmt.Errorf("iterate ConnectionTypeRef: %w", err)
 	}
 
-	log.Printf("migrateParentalGuideRef: read %d rows from ParentsGuide.\"ParentsGuideCategoryRef\"", len(allRows))
+	log.Printf("migrateConnectionTypeRef: read %d rows from References.\"ConnectionTypeRef\"", len(allRows))
 
 	if dryRun {
-		return nil
+		return 0, nil
 	}
 
-	tx, err := newDB.BeginTx(ctx, nil)
-	if err != nil {
-		return fmt.Errorf("begin tx parental_guide_category_ref: %w", err)
-	}
-	defer tx.Rollback()
-
 	const insertSQL = `
-		INSERT INTO parental_guide_category_ref (id, name)
+		INSERT INTO connection_type_ref (id, name)
 		VALUES ($1, $2)
 		ON CONFLICT (id) DO UPDATE
 		SET name = EXCLUDED.name
 	`
 
-	stmt, err := tx.PrepareContext(ctx, insertSQL)
+	w, err := newBatchWriter(ctx, newDB, "refs", "connection_type_ref", []string{insertSQL}, "id", "name")
 	if err != nil {
-		return fmt.Errorf("prepare insert parental_guide_category_ref: %w", err)
+		return 0, err
 	}
-	defer stmt.Close()
 
 	for _, r := range allRows {
-		if _, err := stmt.ExecContext(ctx, r.id, r.name); err != nil {
-			return fmt.Errorf("insert parental_guide_category_ref id=%d: %w", r.id, err)
+		if err := w.add(ctx, r.id, r.id, r.name); err != nil {
+			return 0, err
 		}
 	}
 
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("commit parental_guide_category_ref: %w", err)
+	if err := w.close(ctx); err != nil {
+		return 0, err
 	}
 
-	return nil
+	return w.written, nil
+}
+
+// migrateParentalGuideRef seeds parental_guide_category_ref. The old DB has
+// no category table: the categories are the TitleTable columns the
+// parental-guide phase unpivots, named as in parentalGuideColumns.
+func migrateParentalGuideRef(ctx context.Context, oldDB, newDB *sql.DB, dryRun bool) (int64, error) {
+	log.Printf("migrateParentalGuideRef: %d categories from TitleTable columns", len(parentalGuideColumns))
+
+	if dryRun {
+		return 0, nil
+	}
+
+	const insertSQL = `
+		INSERT INTO parental_guide_category_ref (name)
+		VALUES ($1)
+		ON CONFLICT (name) DO NOTHING
+	`
+
+	w, err := newBatchWriter(ctx, newDB, "refs", "parental_guide_category_ref", []string{insertSQL}, "name")
+	if err != nil {
+		return 0, err
+	}
+
+	for i, col := range parentalGuideColumns {
+		if err := w.add(ctx, int64(i), col.category); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := w.close(ctx); err != nil {
+		return 0, err
+	}
+
+	return w.written, nil
 }
 
-// migrateQualityRef migrates Quality."QualityRef" -> quality_ref.
-func migrateQualityRef(ctx context.Context, oldDB, newDB *sql.DB, dryRun bool) error {
+// migrateQualityRef migrates References."QualityRef" -> quality_ref.
+func migrateQualityRef(ctx context.Context, oldDB, newDB *sql.DB, dryRun bool) (int64, error) {
 	const srcQuery = `
-		SELECT "QualityID", "QualityName"
-		FROM "Quality"."QualityRef"
+		SELECT "QualityID", COALESCE("QualityName", '')
+		FROM "References"."QualityRef"
 		ORDER BY "QualityID"
 	`
 
 	rows, err := oldDB.QueryContext(ctx, srcQuery)
 	if err != nil {
-		return fmt.Errorf("query QualityRef: %w", err)
+		return 0, fmt.Errorf("query QualityRef: %w", err)
 	}
 	defer rows.Close()
 
@@ -607,25 +610,19 @@ func migrateQualityRef(ctx context.Context, oldDB, newDB *sql.DB, dryRun bool) e
 	for rows.Next() {
 		var r qRow
 		if err := rows.Scan(&r.id, &r.name); err != nil {
-			return fmt.Errorf("scan QualityRef row: %w", err)
+			return 0, fmt.Errorf("scan QualityRef row: %w", err)
 		}
 		allRows = append(allRows, r)
 	}
 	if err := rows.Err(); err != nil {
-		return fmt.Errorf("iterate QualityRef: %w", err)
+		return 0, fmt.Errorf("iterate QualityRef: %w", err)
 	}
 
-	log.Printf("migrateQualityRef: read %d rows from Quality.\"QualityRef\"", len(allRows))
+	log.Printf("migrateQualityRef: read %d rows from References.\"QualityRef\"", len(allRows))
 
 	if dryRun {
-		return nil
-	}
-
-	tx, err := newDB.BeginTx(ctx, nil)
-	if err != nil {
-		return fmt.Errorf("begin tx quality_ref: %w", err)
+		return 0, nil
 	}
-	defer tx.Rollback()
 
 	const insertSQL = `
 		INSERT INTO quality_ref (id, name)
@@ -634,36 +631,35 @@ func migrateQualityRef(ctx context.Context, oldDB, newDB *sql.DB, dryRun bool) e
 		SET name = EXCLUDED.name
 	`
 
-	stmt, err := tx.PrepareContext(ctx, insertSQL)
+	w, err := newBatchWriter(ctx, newDB, "refs", "quality_ref", []string{insertSQL}, "id", "name")
 	if err != nil {
-		return fmt.Errorf("prepare insert quality_ref: %w", err)
+		return 0, err
 	}
-	defer stmt.Close()
 
 	for _, r := range allRows {
-		if _, err := stmt.ExecContext(ctx, r.id, r.name); err != nil {
-			return fmt.Errorf("insert quality_ref id=%d: %w", r.id, err)
+		if err := w.add(ctx, r.id, r.id, r.name); err != nil {
+			return 0, err
 		}
 	}
 
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("commit quality_ref: %w", err)
+	if err := w.close(ctx); err != nil {
+		return 0, err
 	}
 
-	return nil
+	return w.written, nil
 }
 
-// migrateDisplayRef migrates Displays."DisplayRef" -> display_ref.
-func migrateDisplayRef(ctx context.Context, oldDB, newDB *sql.DB, dryRun bool) error {
+// migrateDisplayRef migrates References."DisplayRef" -> display_ref.
+func migrateDisplayRef(ctx context.Context, oldDB, newDB *sql.DB, dryRun bool) (int64, error) {
 	const srcQuery = `
-		SELECT "DisplayID", "DisplayName"
-		FROM "Displays"."DisplayRef"
+		SELECT "DisplayID", COALESCE("DisplayType", '')
+		FROM "References"."DisplayRef"
 		ORDER BY "DisplayID"
 	`
 
 	rows, err := oldDB.QueryContext(ctx, srcQuery)
 	if err != nil {
-		return fmt.Errorf("query DisplayRef: %w", err)
+		return 0, fmt.Errorf("query DisplayRef: %w", err)
 	}
 	defer rows.Close()
 
@@ -676,26 +672,20 @@ func migrateDisplayRef(ctx context.Context, oldDB, newDB *sql.DB, dryRun bool) e
 	for rows.Next() {
 		var r dRow
 		if err := rows.Scan(&r.id, &r.name); err != nil {
-			return fmt.Errorf("scan DisplayRef row: %w", err)
+			return 0, fmt.Errorf("scan DisplayRef row: %w", err)
 		}
 		allRows = append(allRows, r)
 	}
 	if err := rows.Err(); err != nil {
-		return fmt.Errorf("iterate DisplayRef: %w", err)
+		return 0, fmt.Errorf("iterate DisplayRef: %w", err)
 	}
 
-	log.Printf("migrateDisplayRef: read %d rows from Displays.\"DisplayRef\"", len(allRows))
+	log.Printf("migrateDisplayRef: read %d rows from References.\"DisplayRef\"", len(allRows))
 
 	if dryRun {
-		return nil
+		return 0, nil
 	}
 
-	tx, err := newDB.BeginTx(ctx, nil)
-	if err != nil {
-		return fmt.Errorf("begin tx display_ref: %w", err)
-	}
-	defer tx.Rollback()
-
 	const insertSQL = `
 		INSERT INTO display_ref (id, name)
 		VALUES ($1, $2)
@@ -703,36 +693,35 @@ func migrateDisplayRef(ctx context.Context, oldDB, newDB *sql.DB, dryRun bool) e
 		SET name = EXCLUDED.name
 	`
 
-	stmt, err := tx.PrepareContext(ctx, insertSQL)
+	w, err := newBatchWriter(ctx, newDB, "refs", "display_ref", []string{insertSQL}, "id", "name")
 	if err != nil {
-		return fmt.Errorf("prepare insert display_ref: %w", err)
+		return 0, err
 	}
-	defer stmt.Close()
 
 	for _, r := range allRows {
-		if _, err := stmt.ExecContext(ctx, r.id, r.name); err != nil {
-			return fmt.Errorf("insert display_ref id=%d: %w", r.id, err)
+		if err := w.add(ctx, r.id, r.id, r.name); err != nil {
+			return 0, err
 		}
 	}
 
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("commit display_ref: %w", err)
+	if err := w.close(ctx); err != nil {
+		return 0, err
 	}
 
-	return nil
+	return w.written, nil
 }
 
-// migrateCastRoleTypeRef migrates Cast."CastRoleTypeRef" -> cast_role_type_ref.
-func migrateCastRoleTypeRef(ctx context.Context, oldDB, newDB *sql.DB, dryRun bool) error {
+// migrateCastRoleTypeRef migrates References."CastTypeRef" -> cast_role_type_ref.
+func migrateCastRoleTypeRef(ctx context.Context, oldDB, newDB *sql.DB, dryRun bool) (int64, error) {
 	const srcQuery = `
-		SELECT "CastRoleTypeID", "CastRoleTypeName"
-		FROM "Cast"."CastRoleTypeRef"
-		ORDER BY "CastRoleTypeID"
+		SELECT "CastTypeID", "CastTypeDescription"
+		FROM "References"."CastTypeRef"
+		ORDER BY "CastTypeID"
 	`
 
 	rows, err := oldDB.QueryContext(ctx, srcQuery)
 	if err != nil {
-		return fmt.Errorf("query CastRoleTypeRef: %w", err)
+		return 0, fmt.Errorf("query CastTypeRef: %w", err)
 	}
 	defer rows.Close()
 
@@ -745,25 +734,19 @@ func migrateCastRoleTypeRef(ctx context.Context, oldDB, newDB *sql.DB, dryRun bo
 	for rows.Next() {
 		var r crtRow
 		if err := rows.Scan(&r.id, &r.name); err != nil {
-			return fmt.Errorf("scan CastRoleTypeRef row: %w", err)
+			return 0, fmt.Errorf("scan CastTypeRef row: %w", err)
 		}
 		allRows = append(allRows, r)
 	}
 	if err := rows.Err(); err != nil {
-		return fmt.Errorf("iterate CastRoleTypeRef: %w", err)
+		return 0, fmt.Errorf("iterate CastTypeRef: %w", err)
 	}
 
-	log.Printf("migrateCastRoleTypeRef: read %d rows from Cast.\"CastRoleTypeRef\"", len(allRows))
+	log.Printf("migrateCastRoleTypeRef: read %d rows from References.\"CastTypeRef\"", len(allRows))
 
 	if dryRun {
-		return nil
-	}
-
-	tx, err := newDB.BeginTx(ctx, nil)
-	if err != nil {
-		return fmt.Errorf("begin tx cast_role_type_ref: %w", err)
+		return 0, nil
 	}
-	defer tx.Rollback()
 
 	const insertSQL = `
 		INSERT INTO cast_role_type_ref (id, name)
@@ -772,36 +755,35 @@ func migrateCastRoleTypeRef(ctx context.Context, oldDB, newDB *sql.DB, dryRun bo
 		SET name = EXCLUDED.name
 	`
 
-	stmt, err := tx.PrepareContext(ctx, insertSQL)
+	w, err := newBatchWriter(ctx, newDB, "refs", "cast_role_type_ref", []string{insertSQL}, "id", "name")
 	if err != nil {
-		return fmt.Errorf("prepare insert cast_role_type_ref: %w", err)
+		return 0, err
 	}
-	defer stmt.Close()
 
 	for _, r := range allRows {
-		if _, err := stmt.ExecContext(ctx, r.id, r.name); err != nil {
-			return fmt.Errorf("insert cast_role_type_ref id=%d: %w", r.id, err)
+		if err := w.add(ctx, r.id, r.id, r.name); err != nil {
+			return 0, err
 		}
 	}
 
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("commit cast_role_type_ref: %w", err)
+	if err := w.close(ctx); err != nil {
+		return 0, err
 	}
 
-	return nil
+	return w.written, nil
 }
 
-// migrateAwardEventRef migrates Awards."AwardEventRef" -> award_event_ref.
-func migrateAwardEventRef(ctx context.Context, oldDB, newDB *sql.DB, dryRun bool) error {
+// migrateAwardEventRef migrates References."AwardEventRef" -> award_event_ref.
+func migrateAwardEventRef(ctx context.Context, oldDB, newDB *sql.DB, dryRun bool) (int64, error) {
 	const srcQuery = `
-		SELECT "AwardEventID", "AwardEventName"
-		FROM "Awards"."AwardEventRef"
-		ORDER BY "AwardEventID"
+		SELECT "EventID", "EventName"
+		FROM "References"."AwardEventRef"
+		ORDER BY "EventID"
 	`
 
 	rows, err := oldDB.QueryContext(ctx, srcQuery)
 	if err != nil {
-		return fmt.Errorf("query AwardEventRef: %w", err)
+		return 0, fmt.Errorf("query AwardEventRef: %w", err)
 	}
 	defer rows.Close()
 
@@ -814,26 +796,20 @@ func migrateAwardEventRef(ctx context.Context, oldDB, newDB *sql.DB, dryRun bool
 	for rows.Next() {
 		var r aeRow
 		if err := rows.Scan(&r.id, &r.name); err != nil {
-			return fmt.Errorf("scan AwardEventRef row: %w", err)
+			return 0, fmt.Errorf("scan AwardEventRef row: %w", err)
 		}
 		allRows = append(allRows, r)
 	}
 	if err := rows.Err(); err != nil {
-		return fmt.Errorf("iterate AwardEventRef: %w", err)
+		return 0, fmt.Errorf("iterate AwardEventRef: %w", err)
 	}
 
-	log.Printf("migrateAwardEventRef: read %d rows from Awards.\"AwardEventRef\"", len(allRows))
+	log.Printf("migrateAwardEventRef: read %d rows from References.\"AwardEventRef\"", len(allRows))
 
 	if dryRun {
-		return nil
+		return 0, nil
 	}
 
-	tx, err := newDB.BeginTx(ctx, nil)
-	if err != nil {
-		return fmt.Errorf("begin tx award_event_ref: %w", err)
-	}
-	defer tx.Rollback()
-
 	const insertSQL = `
 		INSERT INTO award_event_ref (id, name)
 		VALUES ($1, $2)
@@ -841,36 +817,35 @@ func migrateAwardEventRef(ctx context.Context, oldDB, newDB *sql.DB, dryRun bool
 		SET name = EXCLUDED.name
 	`
 
-	stmt, err := tx.PrepareContext(ctx, insertSQL)
+	w, err := newBatchWriter(ctx, newDB, "refs", "award_event_ref", []string{insertSQL}, "id", "name")
 	if err != nil {
-		return fmt.Errorf("prepare insert award_event_ref: %w", err)
+		return 0, err
 	}
-	defer stmt.Close()
 
 	for _, r := range allRows {
-		if _, err := stmt.ExecContext(ctx, r.id, r.name); err != nil {
-			return fmt.Errorf("insert award_event_ref id=%d: %w", r.id, err)
+		if err := w.add(ctx, r.id, r.id, r.name); err != nil {
+			return 0, err
 		}
 	}
 
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("commit award_event_ref: %w", err)
+	if err := w.close(ctx); err != nil {
+		return 0, err
 	}
 
-	return nil
+	return w.written, nil
 }
 
-// migrateAwardNominationTypeRef migrates Awards."AwardNominationTypeRef" -> award_nomination_type_ref.
-func migrateAwardNominationTypeRef(ctx context.Context, oldDB, newDB *sql.DB, dryRun bool) error {
+// migrateAwardNominationTypeRef migrates References."AwardNominationTypeRef" -> award_nomination_type_ref.
+func migrateAwardNominationTypeRef(ctx context.Context, oldDB, newDB *sql.DB, dryRun bool) (int64, error) {
 	const srcQuery = `
-		SELECT "AwardNominationTypeID", "AwardNominationTypeName"
-		FROM "Awards"."AwardNominationTypeRef"
-		ORDER BY "AwardNominationTypeID"
+		SELECT "NominationTypeID", "NominationType"
+		FROM "References"."AwardNominationTypeRef"
+		ORDER BY "NominationTypeID"
 	`
 
 	rows, err := oldDB.QueryContext(ctx, srcQuery)
 	if err != nil {
-		return fmt.Errorf("query AwardNominationTypeRef: %w", err)
+		return 0, fmt.Errorf("query AwardNominationTypeRef: %w", err)
 	}
 	defer rows.Close()
 
@@ -883,25 +858,19 @@ func migrateAwardNominationTypeRef(ctx context.Context, oldDB, newDB *sql.DB, dr
 	for rows.Next() {
 		var r antRow
 		if err := rows.Scan(&r.id, &r.name); err != nil {
-			return fmt.Errorf("scan AwardNominationTypeRef row: %w", err)
+			return 0, fmt.Errorf("scan AwardNominationTypeRef row: %w", err)
 		}
 		allRows = append(allRows, r)
 	}
 	if err := rows.Err(); err != nil {
-		return fmt.Errorf("iterate AwardNominationTypeRef: %w", err)
+		return 0, fmt.Errorf("iterate AwardNominationTypeRef: %w", err)
 	}
 
-	log.Printf("migrateAwardNominationTypeRef: read %d rows from Awards.\"AwardNominationTypeRef\"", len(allRows))
+	log.Printf("migrateAwardNominationTypeRef: read %d rows from References.\"AwardNominationTypeRef\"", len(allRows))
 
 	if dryRun {
-		return nil
-	}
-
-	tx, err := newDB.BeginTx(ctx, nil)
-	if err != nil {
-		return fmt.Errorf("begin tx award_nomination_type_ref: %w", err)
+		return 0, nil
 	}
-	defer tx.Rollback()
 
 	const insertSQL = `
 		INSERT INTO award_nomination_type_ref (id, name)
@@ -910,92 +879,83 @@ func migrateAwardNominationTypeRef(ctx context.Context, oldDB, newDB *sql.DB, dr
 		SET name = EXCLUDED.name
 	`
 
-	stmt, err := tx.PrepareContext(ctx, insertSQL)
+	w, err := newBatchWriter(ctx, newDB, "refs", "award_nomination_type_ref", []string{insertSQL}, "id", "name")
 	if err != nil {
-		return fmt.Errorf("prepare insert award_nomination_type_ref: %w", err)
+		return 0, err
 	}
-	defer stmt.Close()
 
 	for _, r := range allRows {
-		if _, err := stmt.ExecContext(ctx, r.id, r.name); err != nil {
-			return fmt.Errorf("insert award_nomination_type_ref id=%d: %w", r.id, err)
+		if err := w.add(ctx, r.id, r.id, r.name); err != nil {
+			return 0, err
 		}
 	}
 
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("commit award_nomination_type_ref: %w", err)
+	if err := w.close(ctx); err != nil {
+		return 0, err
 	}
 
-	return nil
+	return w.written, nil
 }
 
-// migrateCertificateCountry migrates Certificates."CertificateCountry" -> certificate_country.
-func migrateCertificateCountry(ctx context.Context, oldDB, newDB *sql.DB, dryRun bool) error {
+// migrateCertificateCountry migrates References."CertificateCountryRef" -> certificate_country.
+func migrateCertificateCountry(ctx context.Context, oldDB, newDB *sql.DB, dryRun bool) (int64, error) {
 	const srcQuery = `
-		SELECT "CertificateCountryID", "CertificateID", "CountryID"
-		FROM "Certificates"."CertificateCountry"
-		ORDER BY "CertificateCountryID"
+		SELECT "CountryID", "CertificateID", "Age"
+		FROM "References"."CertificateCountryRef"
+		ORDER BY "CountryID", "CertificateID"
 	`
 
 	rows, err := oldDB.QueryContext(ctx, srcQuery)
 	if err != nil {
-		return fmt.Errorf("query CertificateCountry: %w", err)
+		return 0, fmt.Errorf("query CertificateCountryRef: %w", err)
 	}
 	defer rows.Close()
 
 	type ccRow struct {
-		id           int64
+		countryID     int64
 		certificateID int64
-		countryID    int64
+		age           int64
 	}
 
 	var allRows []ccRow
 	for rows.Next() {
 		var r ccRow
-		if err := rows.Scan(&r.id, &r.certificateID, &r.countryID); err != nil {
-			return fmt.Errorf("scan CertificateCountry row: %w", err)
+		if err := rows.Scan(&r.countryID, &r.certificateID, &r.age); err != nil {
+			return 0, fmt.Errorf("scan CertificateCountryRef row: %w", err)
 		}
 		allRows = append(allRows, r)
 	}
 	if err := rows.Err(); err != nil {
-		return fmt.Errorf("iterate CertificateCountry: %w", err)
+		return 0, fmt.Errorf("iterate CertificateCountryRef: %w", err)
 	}
 
-	log.Printf("migrateCertificateCountry: read %d rows from Certificates.\"CertificateCountry\"", len(allRows))
+	log.Printf("migrateCertificateCountry: read %d rows from References.\"CertificateCountryRef\"", len(allRows))
 
 	if dryRun {
-		return nil
-	}
-
-	tx, err := newDB.BeginTx(ctx, nil)
-	if err != nil {
-		return fmt.Errorf("begin tx certificate_country: %w", err)
+		return 0, nil
 	}
-	defer tx.Rollback()
 
 	const insertSQL = `
-		INSERT INTO certificate_country (id, certificate_id, country_id)
+		INSERT INTO certificate_country (country_id, certificate_id, min_age)
 		VALUES ($1, $2, $3)
-		ON CONFLICT (id) DO UPDATE
-		SET certificate_id = EXCLUDED.certificate_id,
-		    country_id = EXCLUDED.country_id
+		ON CONFLICT (country_id, certificate_id) DO UPDATE
+		SET min_age = EXCLUDED.min_age
 	`
 
-	stmt, err := tx.PrepareContext(ctx, insertSQL)
+	w, err := newBatchWriter(ctx, newDB, "refs", "certificate_country", []string{insertSQL}, "country_id", "certificate_id", "min_age")
 	if err != nil {
-		return fmt.Errorf("prepare insert certificate_country: %w", err)
+		return 0, err
 	}
-	defer stmt.Close()
 
 	for _, r := range allRows {
-		if _, err := stmt.ExecContext(ctx, r.id, r.certificateID, r.countryID); err != nil {
-			return fmt.Errorf("insert certificate_country id=%d: %w", r.id, err)
+		if err := w.add(ctx, r.countryID, r.countryID, r.certificateID, r.age); err != nil {
+			return 0, err
 		}
 	}
 
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("commit certificate_country: %w", err)
+	if err := w.close(ctx); err != nil {
+		return 0, err
 	}
 
-	return nil
+	return w.written, nil
 }
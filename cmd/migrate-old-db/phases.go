@@ -0,0 +1,254 @@
+// cmd/migrate-old-db/phases.go
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// phaseFunc is the signature every Migrate*Phase function shares.
+type phaseFunc func(ctx context.Context, oldDB, newDB *sql.DB, dryRun bool) error
+
+// phaseSpec registers one -phase value and the phases whose rows it reads.
+type phaseSpec struct {
+	name string
+	deps []string
+	run  phaseFunc
+	// explicit phases are left out of "all" and "X+"; they only run when
+	// named on their own.
+	explicit bool
+}
+
+// phaseRegistry lists every runnable phase. Its order is the tie-breaker when
+// the planner is free to pick, so keep it close to the natural run order.
+var phaseRegistry = []phaseSpec{
+	{name: "refs", run: MigrateRefsPhase},
+	{name: "core-persons", run: MigrateCorePersonsPhase},
+	{name: "core-title", deps: []string{"refs"}, run: MigrateCoreTitlesPhase},
+	{name: "episode-links", deps: []string{"core-title"}, run: MigrateEpisodeLinksPhase},
+	{name: "companies", deps: []string{"core-title"}, run: MigrateCompaniesPhase},
+	{name: "parental-guide", deps: []string{"refs", "core-title"}, run: MigrateParentalGuidePhase},
+	{name: "media-files", deps: []string{"refs", "core-title"}, run: MigrateMediaFilesPhase},
+	{name: "queues", deps: []string{"core-title", "imdb-ids"}, run: MigrateQueuesPhase},
+	{name: "junctions-country", deps: []string{"refs", "core-title"}, run: MigrateJunctionsCountryPhase},
+	{name: "junctions-language", deps: []string{"refs", "core-title"}, run: MigrateJunctionsLanguagePhase},
+	{name: "junctions-genre", deps: []string{"refs", "core-title"}, run: MigrateJunctionsGenrePhase},
+	{name: "junctions-alias", deps: []string{"core-title"}, run: MigrateJunctionsAliasPhase},
+	{name: "junctions-certificate", deps: []string{"refs", "core-title"}, run: MigrateJunctionsCertificatePhase},
+	{name: "junctions-cast", deps: []string{"refs", "core-persons", "core-title"}, run: MigrateJunctionsCastPhase},
+	{name: "junctions-award", deps: []string{"refs", "core-persons", "core-title"}, run: MigrateJunctionsAwardPhase},
+	{name: "junctions-connection", deps: []string{"refs", "core-title"}, run: MigrateJunctionsConnectionPhase},
+	{name: "imdb-ids", deps: []string{"core-persons", "core-title", "junctions-cast"}, run: MigrateImdbIDsPhase},
+	{name: "id-map-report", deps: []string{"refs", "core-persons", "core-title"}, run: MigrateIDMapReportPhase},
+	{name: "finalize", deps: []string{
+		"refs", "core-persons", "core-title", "episode-links", "companies", "parental-guide", "media-files", "queues",
+		"junctions-country", "junctions-language", "junctions-genre", "junctions-alias",
+		"junctions-certificate", "junctions-cast", "junctions-award", "junctions-connection", "imdb-ids",
+	}, run: MigrateFinalizePhase},
+	// verify exits non-zero on drift, so it runs last: after finalize and
+	// every phase that writes the rows it compares.
+	{name: "verify", deps: []string{
+		"finalize", "core-persons", "core-title", "episode-links", "companies", "parental-guide", "media-files", "queues",
+		"junctions-country", "junctions-language", "junctions-genre", "junctions-alias",
+		"junctions-certificate", "junctions-cast", "junctions-award", "junctions-connection", "imdb-ids",
+	}, run: MigrateVerifyPhase},
+	{name: "sync", deps: []string{"refs", "core-persons", "core-title"}, run: MigrateSyncPhase, explicit: true},
+}
+
+// phaseGroups are -phase names that stand for several registered phases.
+var phaseGroups = map[string][]string{
+	"junctions": {
+		"junctions-country",
+		"junctions-language",
+		"junctions-genre",
+		"junctions-alias",
+		"junctions-certificate",
+		"junctions-cast",
+		"junctions-award",
+		"junctions-connection",
+	},
+}
+
+// phaseNames is the list shown in the -phase help text.
+func phaseNames() string {
+	names := make([]string, 0, len(phaseRegistry)+len(phaseGroups)+1)
+	for _, p := range phaseRegistry {
+		names = append(names, p.name)
+	}
+	groups := make([]string, 0, len(phaseGroups))
+	for g := range phaseGroups {
+		groups = append(groups, g)
+	}
+	sort.Strings(groups)
+	names = append(names, groups...)
+	names = append(names, "all")
+	return strings.Join(names, " | ")
+}
+
+func lookupPhase(name string) (phaseSpec, bool) {
+	for _, p := range phaseRegistry {
+		if p.name == name {
+			return p, true
+		}
+	}
+	return phaseSpec{}, false
+}
+
+// resolvePlan turns a -phase value into the phases to run, in dependency order:
+//
+//	all      every registered phase except the explicit ones
+//	X        X only (a group: its members); dependencies are assumed done
+//	X+       X plus every non-explicit phase that depends on it, directly or not
+func resolvePlan(arg string) ([]phaseSpec, error) {
+	if err := checkPhaseRegistry(); err != nil {
+		return nil, err
+	}
+
+	arg = strings.TrimSpace(arg)
+	withDependents := strings.HasSuffix(arg, "+")
+	name := strings.TrimSuffix(arg, "+")
+
+	selected := make(map[string]bool)
+	switch {
+	case name == "all":
+		for _, p := range phaseRegistry {
+			if !p.explicit {
+				selected[p.name] = true
+			}
+		}
+	case phaseGroups[name] != nil:
+		for _, member := range phaseGroups[name] {
+			selected[member] = true
+		}
+	default:
+		if _, ok := lookupPhase(name); !ok {
+			return nil, fmt.Errorf("unknown phase %q (want %s, optionally with a trailing +)", arg, phaseNames())
+		}
+		selected[name] = true
+	}
+
+	if withDependents {
+		// Grow the selection until no unselected phase depends on it.
+		for changed := true; changed; {
+			changed = false
+			for _, p := range phaseRegistry {
+				if selected[p.name] || p.explicit {
+					continue
+				}
+				for _, d := range p.deps {
+					if selected[d] {
+						selected[p.name] = true
+						changed = true
+						break
+					}
+				}
+			}
+		}
+	}
+
+	return orderPhases(selected), nil
+}
+
+// orderPhases returns the selected phases so that every phase comes after the
+// selected phases it depends on. Among ready phases registry order wins.
+func orderPhases(selected map[string]bool) []phaseSpec {
+	done := make(map[string]bool)
+	var plan []phaseSpec
+	for len(plan) < len(selected) {
+		for _, p := range phaseRegistry {
+			if !selected[p.name] || done[p.name] {
+				continue
+			}
+			ready := true
+			for _, d := range p.deps {
+				if selected[d] && !done[d] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				done[p.name] = true
+				plan = append(plan, p)
+				break
+			}
+		}
+	}
+	return plan
+}
+
+// checkPhaseRegistry rejects unknown dependencies and cycles, which would
+// otherwise leave orderPhases looping.
+func checkPhaseRegistry() error {
+	index := make(map[string]int, len(phaseRegistry))
+	for i, p := range phaseRegistry {
+		if _, dup := index[p.name]; dup {
+			return fmt.Errorf("phase registry: %q registered twice", p.name)
+		}
+		index[p.name] = i
+	}
+	for _, p := range phaseRegistry {
+		for _, d := range p.deps {
+			if _, ok := index[d]; !ok {
+				return fmt.Errorf("phase registry: %q depends on unknown phase %q", p.name, d)
+			}
+		}
+	}
+	for _, p := range phaseRegistry {
+		if _, ok := phaseSchema[p.name]; !ok {
+			return fmt.Errorf("phase registry: %q has no phaseSchema entry (preflight.go)", p.name)
+		}
+	}
+	for g, members := range phaseGroups {
+		for _, m := range members {
+			if _, ok := index[m]; !ok {
+				return fmt.Errorf("phase registry: group %q lists unknown phase %q", g, m)
+			}
+		}
+	}
+
+	// Depth-first search; a phase seen again while still on the stack is a cycle.
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(phaseRegistry))
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case visiting:
+			return fmt.Errorf("phase registry: dependency cycle %s -> %s", strings.Join(path, " -> "), name)
+		case visited:
+			return nil
+		}
+		state[name] = visiting
+		for _, d := range phaseRegistry[index[name]].deps {
+			if err := visit(d, append(path, name)); err != nil {
+				return err
+			}
+		}
+		state[name] = visited
+		return nil
+	}
+	for _, p := range phaseRegistry {
+		if err := visit(p.name, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// printPlan writes the resolved execution order for -plan.
+func printPlan(arg string, plan []phaseSpec) {
+	fmt.Printf("Execution plan for -phase %s (%d phases):\n", arg, len(plan))
+	for i, p := range plan {
+		deps := "-"
+		if len(p.deps) > 0 {
+			deps = strings.Join(p.deps, ", ")
+		}
+		fmt.Printf("  %2d. %-22s depends on: %s\n", i+1, p.name, deps)
+	}
+}
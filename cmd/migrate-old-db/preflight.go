@@ -0,0 +1,387 @@
+// cmd/migrate-old-db/preflight.go
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+)
+
+// tableUse is one table a phase reads or writes, with the columns it names.
+type tableUse struct {
+	old     bool // true: old DB, false: new DB
+	schema  string
+	table   string
+	columns []string
+}
+
+func (u tableUse) String() string {
+	side := "new"
+	if u.old {
+		side = "old"
+	}
+	return fmt.Sprintf("%s %q.%q", side, u.schema, u.table)
+}
+
+func oldTable(schema, table string, columns ...string) tableUse {
+	return tableUse{old: true, schema: schema, table: table, columns: columns}
+}
+
+func newTable(table string, columns ...string) tableUse {
+	return tableUse{schema: "public", table: table, columns: columns}
+}
+
+// Column lists shared by several phases.
+var (
+	oldTitleColumns = []string{
+		"TitleID", "TitleType", "TitleName", "OriginalTitle", "TitleYear", "TitleLength",
+		"TitleCountry", "PosterURL", "MetacriticRating", "Revenue", "IMDbRating", "IMDbVotes",
+		"Popularity", "ParentID", "EpisodeSeason", "EpisodeNumber", "TotalSeasons", "TotalEpisodes",
+		"DateReleased", "DateAdded", "DateUpdated", "Available", "Viewed", "Played", "Liked",
+		"UnLiked", "FolderName", "FolderPath", "TitleSummary", "TitleStoryLine", "TitleCategory",
+	}
+	oldPersonColumns = []string{
+		"CastID", "CastName", "IsDirector", "IsWriter", "IsCharacter", "CastImageURL", "CastDescription",
+	}
+	newPersonColumns = []string{"id", "name", "primary_profession", "image_url", "bio", "created_at", "updated_at"}
+	idMapColumns     = []string{"entity", "old_id", "new_id", "match_method", "updated_at"}
+	rejectColumns    = []string{"phase", "target", "source_key", "error", "source_row"}
+)
+
+// phaseSchema lists, per registered phase, every table and column the phase's
+// SQL names. Keep it in step with the queries when a phase changes.
+var phaseSchema = map[string][]tableUse{
+	"refs": {
+		oldTable("References", "CountryRef", "CountryID", "CountryName", "CountryCode"),
+		oldTable("References", "LanguageRef", "LanguageID", "LanguageName", "LanguageCode"),
+		oldTable("References", "GenreRef", "GenreID", "GenreName"),
+		oldTable("References", "CategoryRef", "CategoryID", "CategoryDecription"),
+		oldTable("References", "CertificateRef", "CertificateID", "CertificateName"),
+		oldTable("References", "CertificateCountryRef", "CountryID", "CertificateID", "Age"),
+		oldTable("References", "TitleTypeRef", "TypeID", "TypeName"),
+		oldTable("References", "ConnectionTypeRef", "ConnectionTypeID", "ConnectionTypeDescription"),
+		oldTable("References", "QualityRef", "QualityID", "QualityName"),
+		oldTable("References", "DisplayRef", "DisplayID", "DisplayType"),
+		oldTable("References", "CastTypeRef", "CastTypeID", "CastTypeDescription"),
+		oldTable("References", "AwardEventRef", "EventID", "EventName"),
+		oldTable("References", "AwardNominationTypeRef", "NominationTypeID", "NominationType"),
+		newTable("country_ref", "id", "name", "iso2_code", "iso3_code"),
+		newTable("language_ref", "id", "name", "iso_code"),
+		newTable("genre_ref", "id", "name"),
+		newTable("category_ref", "id", "name"),
+		newTable("certificate_ref", "id", "name"),
+		newTable("certificate_country", "country_id", "certificate_id", "min_age"),
+		newTable("title_type_ref", "id", "name"),
+		newTable("connection_type_ref", "id", "name"),
+		newTable("parental_guide_category_ref", "id", "name"),
+		newTable("quality_ref", "id", "name"),
+		newTable("display_ref", "id", "name"),
+		newTable("cast_role_type_ref", "id", "name"),
+		newTable("award_event_ref", "id", "name"),
+		newTable("award_nomination_type_ref", "id", "name"),
+		newTable("id_map", idMapColumns...),
+		newTable("migration_reject", rejectColumns...),
+	},
+	"core-persons": {
+		oldTable("Tables", "CastTable", oldPersonColumns...),
+		newTable("person", newPersonColumns...),
+		newTable("id_map", idMapColumns...),
+		newTable("migration_checkpoint", "phase", "step", "last_key", "rows_done", "completed_at", "updated_at"),
+		newTable("migration_reject", rejectColumns...),
+	},
+	"core-title": {
+		oldTable("Tables", "TitleTable", oldTitleColumns...),
+		newTable("title", titleInsertColumns...),
+		newTable("id_map", idMapColumns...),
+		newTable("migration_checkpoint", "phase", "step", "last_key", "rows_done", "completed_at", "updated_at"),
+		newTable("migration_reject", rejectColumns...),
+	},
+	"episode-links": {
+		oldTable("Tables", "TitleTable", "TitleID", "PreviousTitleID", "NextTitleID"),
+		newTable("title", "id", "parent_title_id", "season_number", "episode_number", "next_episode_id"),
+		newTable("migration_reject", rejectColumns...),
+	},
+	"companies": {
+		oldTable("Tables", "CompanyTable", "CompanyID", "CompanyName"),
+		oldTable("public", "CompanyTable", "CompanyID", "CompanyName"),
+		oldTable("Lines", "CompanyTitleLine", "TitleID", "CompanyID"),
+		newTable("company", "id", "name"),
+		newTable("title_company", "title_id", "company_id"),
+		newTable("title", "id"),
+		newTable("migration_reject", rejectColumns...),
+	},
+	"parental-guide": {
+		oldTable("Tables", "TitleTable", "TitleID", "Nudity", "Violence", "Profanity", "AlcoholDrugSmoking", "Frightening"),
+		oldTable("References", "ParentGuideRef", "ParentGuideID", "ParentGuideDescription"),
+		newTable("parental_guide_category_ref", "id", "name"),
+		newTable("title_parental_guide", "title_id", "category_id", "severity", "description"),
+		newTable("title", "id"),
+		newTable("migration_reject", rejectColumns...),
+	},
+	"media-files": {
+		oldTable("Lines", "FileTitleLine", "TitleID", "QualityID", "DisplayID", "AudioLanguageID", "SubtitleLanguageID"),
+		oldTable("Tables", "TitleTable", "TitleID", "FolderPath", "FolderName"),
+		newTable("id_map", idMapColumns...),
+		newTable("media_file", "title_id", "quality_id", "display_id", "file_path",
+			"audio_language_id", "subtitle_language_id", "is_missing", "last_checked_at", "updated_at"),
+		newTable("title", "id"),
+		newTable("migration_reject", rejectColumns...),
+	},
+	"queues": {
+		oldTable("Tables", "RequestedTitles", "TitleID"),
+		oldTable("Tables", "NotDownloaded", "TitleID"),
+		oldTable("Tables", "ToBeUpdated", "TitleID"),
+		oldTable("Tables", "TitleTable", "TitleID", "TitleName"),
+		newTable("requested_title", "title_id", "old_title_id", "imdb_id", "title_name", "notes"),
+		newTable("not_downloaded_title", "title_id", "old_title_id", "imdb_id", "title_name", "reason"),
+		newTable("title_refresh_queue", "title_id", "reason"),
+		newTable("title", "id", "imdb_id"),
+		newTable("migration_reject", rejectColumns...),
+	},
+	"junctions-country": {
+		newTable("id_map", idMapColumns...),
+		oldTable("Lines", "CountryTitleLine", "TitleID", "CountryID"),
+		newTable("title_country", "title_id", "country_id"),
+		newTable("title", "id"),
+		newTable("migration_reject", rejectColumns...),
+	},
+	"junctions-language": {
+		newTable("id_map", idMapColumns...),
+		oldTable("Lines", "LanguageTitleLine", "TitleID", "LanguageID"),
+		oldTable("Tables", "TitleTable", "TitleID", "TitleLanguage"),
+		newTable("title_language", "title_id", "language_id", "is_original"),
+		newTable("title", "id"),
+		newTable("migration_reject", rejectColumns...),
+	},
+	"junctions-genre": {
+		newTable("id_map", idMapColumns...),
+		oldTable("Lines", "GenreTitleLine", "TitleID", "GenreID"),
+		newTable("title_genre", "title_id", "genre_id"),
+		newTable("title", "id"),
+		newTable("migration_reject", rejectColumns...),
+	},
+	"junctions-alias": {
+		oldTable("Lines", "KnownAsTitleLine", "TitleID", "KnownAs"),
+		newTable("id_map", idMapColumns...),
+		newTable("title_alias", "title_id", "alias"),
+		newTable("migration_reject", rejectColumns...),
+	},
+	"junctions-certificate": {
+		newTable("id_map", idMapColumns...),
+		oldTable("Lines", "CertificateTitleLine", "TitleID", "CertificateID", "CountryID"),
+		oldTable("Tables", "TitleTable", "TitleID", "TitleCertificate", "TitleCountry"),
+		newTable("title_certificate", "title_id", "certificate_id", "country_id"),
+		newTable("title", "id"),
+		newTable("migration_reject", rejectColumns...),
+	},
+	"junctions-cast": {
+		newTable("id_map", idMapColumns...),
+		oldTable("Lines", "CastTitleLine", "TitleID", "CastID", "CastType", "CastRole", "Sequence"),
+		newTable("title_cast", "title_id", "person_id", "role_type_id", "character_name", "billing_order"),
+		newTable("title", "id"),
+		newTable("person", "id"),
+		newTable("migration_reject", rejectColumns...),
+	},
+	"junctions-award": {
+		newTable("id_map", idMapColumns...),
+		oldTable("Lines", "AwardTitleLine", "TitleID", "EventID", "CastID", "AwardYear", "NominationType", "Description", "Category"),
+		newTable("title_award", "title_id", "person_id", "event_id", "nomination_type_id", "award_year", "description", "category"),
+		newTable("title", "id"),
+		newTable("person", "id"),
+		newTable("migration_reject", rejectColumns...),
+	},
+	"junctions-connection": {
+		newTable("id_map", idMapColumns...),
+		oldTable("Lines", "ConnectionTitleLine", "TitleID", "ConnectionTitleID", "ConnectionType"),
+		oldTable("Lines", "SimilaritiesTitleLine", "TitleID", "SimilarTitleID"),
+		newTable("title_connection", "title_id", "other_title_id", "connection_type_id"),
+		newTable("title_similarity", "title_id", "similar_title_id"),
+		newTable("title", "id"),
+		newTable("migration_reject", rejectColumns...),
+	},
+	"sync": {
+		oldTable("Tables", "TitleTable", oldTitleColumns...),
+		oldTable("Tables", "TitleTable", "TitleLanguage", "TitleCertificate"),
+		oldTable("Tables", "CastTable", oldPersonColumns...),
+		oldTable("Lines", "CountryTitleLine", "TitleID", "CountryID"),
+		oldTable("Lines", "LanguageTitleLine", "TitleID", "LanguageID"),
+		oldTable("Lines", "GenreTitleLine", "TitleID", "GenreID"),
+		oldTable("Lines", "CertificateTitleLine", "TitleID", "CertificateID", "CountryID"),
+		oldTable("Lines", "CastTitleLine", "TitleID", "CastID", "CastType", "CastRole", "Sequence"),
+		oldTable("Lines", "KnownAsTitleLine", "TitleID", "KnownAs"),
+		oldTable("Lines", "AwardTitleLine", "TitleID", "EventID", "CastID", "AwardYear", "NominationType", "Description", "Category"),
+		oldTable("Lines", "ConnectionTitleLine", "TitleID", "ConnectionTitleID", "ConnectionType"),
+		oldTable("Lines", "SimilaritiesTitleLine", "TitleID", "SimilarTitleID"),
+		oldTable("Lines", "CompanyTitleLine", "TitleID", "CompanyID"),
+		oldTable("Lines", "FileTitleLine", "TitleID", "QualityID", "DisplayID", "AudioLanguageID", "SubtitleLanguageID"),
+		oldTable("Tables", "TitleTable", "FolderPath", "FolderName", "PreviousTitleID", "NextTitleID"),
+		oldTable("Tables", "TitleTable", "Nudity", "Violence", "Profanity", "AlcoholDrugSmoking", "Frightening"),
+		oldTable("References", "ParentGuideRef", "ParentGuideID", "ParentGuideDescription"),
+		oldTable("Tables", "CompanyTable", "CompanyID", "CompanyName"),
+		oldTable("public", "CompanyTable", "CompanyID", "CompanyName"),
+		newTable("title", titleInsertColumns...),
+		newTable("title", "next_episode_id"),
+		newTable("person", newPersonColumns...),
+		newTable("title_country", "title_id", "country_id"),
+		newTable("title_language", "title_id", "language_id", "is_original"),
+		newTable("title_genre", "title_id", "genre_id"),
+		newTable("title_certificate", "title_id", "certificate_id", "country_id"),
+		newTable("title_cast", "title_id", "person_id", "role_type_id", "character_name", "billing_order"),
+		newTable("title_alias", "title_id", "alias"),
+		newTable("title_award", "title_id", "person_id", "event_id", "nomination_type_id", "award_year", "description", "category"),
+		newTable("title_connection", "title_id", "other_title_id", "connection_type_id"),
+		newTable("title_similarity", "title_id", "similar_title_id"),
+		newTable("company", "id", "name"),
+		newTable("title_company", "title_id", "company_id"),
+		newTable("media_file", "title_id", "quality_id", "display_id", "file_path",
+			"audio_language_id", "subtitle_language_id", "updated_at"),
+		newTable("parental_guide_category_ref", "id", "name"),
+		newTable("title_parental_guide", "title_id", "category_id", "severity", "description"),
+		newTable("id_map", idMapColumns...),
+		newTable("migration_reject", rejectColumns...),
+		newTable("sync_state", "source", "watermark", "updated_at"),
+		newTable("sync_row_hash", "source", "key_id", "row_hash"),
+	},
+	"imdb-ids": {
+		newTable("title", "id", "imdb_id", "primary_title", "original_title", "start_year", "poster_url", "folder_name"),
+		newTable("person", "id", "imdb_id", "name", "birth_year", "image_url"),
+		newTable("title_cast", "title_id", "person_id"),
+		newTable("imdb_id_review", "entity", "entity_id", "source", "candidates", "reason"),
+	},
+	// finalize finds its tables through information_schema.
+	"finalize": {},
+	"id-map-report": {
+		newTable("id_map", idMapColumns...),
+	},
+	// verify reads the old side through the sync jobs (see verifyJunction).
+	"verify": {
+		oldTable("Tables", "TitleTable", "TitleID", "TitleLanguage", "TitleCertificate", "TitleCountry", "FolderPath", "FolderName"),
+		oldTable("Tables", "TitleTable", "Nudity", "Violence", "Profanity", "AlcoholDrugSmoking", "Frightening"),
+		oldTable("Tables", "CastTable", "CastID"),
+		oldTable("Lines", "CountryTitleLine", "TitleID", "CountryID"),
+		oldTable("Lines", "LanguageTitleLine", "TitleID", "LanguageID"),
+		oldTable("Lines", "GenreTitleLine", "TitleID", "GenreID"),
+		oldTable("Lines", "CertificateTitleLine", "TitleID", "CertificateID", "CountryID"),
+		oldTable("Lines", "CastTitleLine", "TitleID", "CastID", "CastType", "CastRole", "Sequence"),
+		oldTable("Lines", "KnownAsTitleLine", "TitleID", "KnownAs"),
+		oldTable("Lines", "AwardTitleLine", "TitleID", "EventID", "CastID", "AwardYear", "NominationType", "Description", "Category"),
+		oldTable("Lines", "ConnectionTitleLine", "TitleID", "ConnectionTitleID", "ConnectionType"),
+		oldTable("Lines", "SimilaritiesTitleLine", "TitleID", "SimilarTitleID"),
+		oldTable("Lines", "CompanyTitleLine", "TitleID", "CompanyID"),
+		oldTable("Lines", "FileTitleLine", "TitleID", "QualityID", "DisplayID", "AudioLanguageID", "SubtitleLanguageID"),
+		oldTable("References", "ParentGuideRef", "ParentGuideID", "ParentGuideDescription"),
+		oldTable("Tables", "CompanyTable", "CompanyID", "CompanyName"),
+		oldTable("public", "CompanyTable", "CompanyID", "CompanyName"),
+		newTable("title", titleInsertColumns...),
+		newTable("person", newPersonColumns...),
+		newTable("id_map", idMapColumns...),
+		newTable("parental_guide_category_ref", "id", "name"),
+		newTable("title_country", "title_id"),
+		newTable("title_language", "title_id"),
+		newTable("title_genre", "title_id"),
+		newTable("title_certificate", "title_id"),
+		newTable("title_cast", "title_id"),
+		newTable("title_award", "title_id"),
+		newTable("title_alias", "title_id"),
+		newTable("title_company", "title_id"),
+		newTable("title_connection", "title_id"),
+		newTable("title_similarity", "title_id"),
+		newTable("title_parental_guide", "title_id"),
+		newTable("media_file", "title_id"),
+	},
+}
+
+// runPreflight checks every table and column the planned phases name against
+// information_schema on both DBs, before anything is written. All mismatches
+// are logged together; any mismatch fails the run.
+func runPreflight(ctx context.Context, oldDB, newDB *sql.DB, plan []phaseSpec) error {
+	log.Printf("--- Preflight: checking tables and columns of %d phase(s) ---", len(plan))
+
+	oldCols, err := loadSchemaColumns(ctx, oldDB)
+	if err != nil {
+		return fmt.Errorf("preflight: read old information_schema: %w", err)
+	}
+	newCols, err := loadSchemaColumns(ctx, newDB)
+	if err != nil {
+		return fmt.Errorf("preflight: read new information_schema: %w", err)
+	}
+
+	// The same table can be listed by several phases; report it once.
+	seen := make(map[string]bool)
+	var problems []string
+	checked := 0
+	for _, p := range plan {
+		for _, u := range phaseSchema[p.name] {
+			cols := newCols
+			if u.old {
+				cols = oldCols
+			}
+			table, ok := cols[u.schema+"."+u.table]
+			if !ok {
+				key := u.String()
+				if !seen[key] {
+					seen[key] = true
+					problems = append(problems, fmt.Sprintf("%-22s missing table  %s", p.name, key))
+				}
+				continue
+			}
+			for _, c := range u.columns {
+				checked++
+				key := fmt.Sprintf("%s.%q", u, c)
+				if table[c] || seen[key] {
+					continue
+				}
+				seen[key] = true
+				problems = append(problems, fmt.Sprintf("%-22s missing column %s (has: %s)", p.name, key, columnList(table)))
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		for _, msg := range problems {
+			log.Printf("PREFLIGHT: %s", msg)
+		}
+		return fmt.Errorf("preflight: %d schema mismatches, nothing was written (use -preflight=false to skip)", len(problems))
+	}
+	log.Printf("--- Preflight OK: %d columns checked ---", checked)
+	return nil
+}
+
+// loadSchemaColumns returns "schema.table" -> set of column names.
+func loadSchemaColumns(ctx context.Context, db *sql.DB) (map[string]map[string]bool, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT table_schema, table_name, column_name
+		FROM information_schema.columns
+		WHERE table_schema NOT IN ('pg_catalog', 'information_schema')
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make(map[string]map[string]bool)
+	for rows.Next() {
+		var schema, table, column string
+		if err := rows.Scan(&schema, &table, &column); err != nil {
+			return nil, err
+		}
+		key := schema + "." + table
+		if out[key] == nil {
+			out[key] = make(map[string]bool)
+		}
+		out[key][column] = true
+	}
+	return out, rows.Err()
+}
+
+func columnList(cols map[string]bool) string {
+	names := make([]string, 0, len(cols))
+	for c := range cols {
+		names = append(names, c)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,313 @@
+// cmd/migrate-old-db/runlog.go
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	logFormatText = "text"
+	logFormatJSON = "json"
+)
+
+// jsonLogWriter is the log output with -log-format=json: every log line
+// becomes one JSON object tagged with the running phase. logProgress and
+// logStepDone lines also carry the step and its counts as fields.
+type jsonLogWriter struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+type logEntry struct {
+	Time  string `json:"time"`
+	Level string `json:"level"`
+	Phase string `json:"phase,omitempty"`
+	Step  string `json:"step,omitempty"`
+	*stepCounts
+	Msg string `json:"msg"`
+}
+
+// stepCounts is how far a step got: rows processed and skipped, rows/s and
+// seconds since the step started.
+type stepCounts struct {
+	Processed int64   `json:"processed"`
+	Skipped   int64   `json:"skipped"`
+	Rate      float64 `json:"rate"`
+	Elapsed   float64 `json:"elapsed"`
+}
+
+var jsonLog *jsonLogWriter
+
+// useJSONLog switches the standard logger to JSON lines on stdout.
+func useJSONLog() {
+	jsonLog = &jsonLogWriter{out: os.Stdout}
+	log.SetFlags(0)
+	log.SetOutput(jsonLog)
+}
+
+// Write wraps one log.Printf line. A WARN: / ERROR: prefix becomes the level.
+func (w *jsonLogWriter) Write(p []byte) (int, error) {
+	msg := string(bytes.TrimRight(p, "\n"))
+	level := "info"
+	for _, l := range []string{"WARN", "ERROR"} {
+		if rest, ok := strings.CutPrefix(msg, l+":"); ok {
+			level, msg = strings.ToLower(l), strings.TrimSpace(rest)
+			break
+		}
+	}
+	if err := w.write(logEntry{Level: level, Phase: run.currentPhase(), Msg: msg}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *jsonLogWriter) write(e logEntry) error {
+	e.Time = time.Now().UTC().Format(time.RFC3339Nano)
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, err = w.out.Write(append(line, '\n'))
+	return err
+}
+
+// logProgress logs a progress line of step. In text mode it is just the
+// formatted message; with -log-format=json the counts go along as fields.
+func logProgress(step string, processed, skipped int64, start time.Time, format string, args ...interface{}) {
+	if jsonLog == nil {
+		log.Printf(format, args...)
+		return
+	}
+	elapsed := time.Since(start)
+	err := jsonLog.write(logEntry{
+		Level: "info",
+		Phase: run.currentPhase(),
+		Step:  step,
+		stepCounts: &stepCounts{
+			Processed: processed,
+			Skipped:   skipped,
+			Rate:      rowsPerSecond(processed, elapsed),
+			Elapsed:   elapsed.Seconds(),
+		},
+		Msg: fmt.Sprintf(format, args...),
+	})
+	if err != nil {
+		log.Printf("WARN: write JSON log line: %v", err)
+	}
+}
+
+// logStepDone is logProgress for the final line of step; it also adds the
+// step's counts to the migration_run row.
+func logStepDone(step string, processed, skipped int64, start time.Time, format string, args ...interface{}) {
+	run.addStep(step, processed, skipped, time.Since(start))
+	logProgress(step, processed, skipped, start, format, args...)
+}
+
+// migrationRun is the migration_run row of this invocation. Phases and steps
+// are filled in as they finish; finish writes them out with the outcome.
+type migrationRun struct {
+	mu       sync.Mutex
+	id       int64
+	phase    string // running phase, for log lines
+	phases   []*phaseRun
+	rejected int64
+}
+
+type phaseRun struct {
+	Phase    string     `json:"phase"`
+	Outcome  string     `json:"outcome"`
+	Elapsed  float64    `json:"elapsed"`
+	Rejected int64      `json:"rejected,omitempty"`
+	Steps    []*stepRun `json:"steps,omitempty"`
+
+	start time.Time
+}
+
+type stepRun struct {
+	Step string `json:"step"`
+	stepCounts
+}
+
+var run = &migrationRun{}
+
+// begin inserts the 'running' row for this invocation.
+func (r *migrationRun) begin(ctx context.Context, newDB *sql.DB, planned []phaseSpec) error {
+	names := make([]string, len(planned))
+	for i, p := range planned {
+		names[i] = p.name
+	}
+	if err := newDB.QueryRowContext(ctx, `
+		INSERT INTO migration_run (args, phase, phases, dry_run)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`, pq.Array(redactArgs(os.Args[1:])), *phase, pq.Array(names), *dryRun).Scan(&r.id); err != nil {
+		return fmt.Errorf("insert migration_run: %w", err)
+	}
+	log.Printf("migration_run id=%d", r.id)
+	return nil
+}
+
+// dsnFlags are the flags whose values carry database passwords.
+var dsnFlags = map[string]bool{"old": true, "new": true}
+
+// redactArgs returns args with the password of every -old/-new DSN masked,
+// in both the "-old DSN" and "-old=DSN" forms.
+func redactArgs(args []string) []string {
+	out := make([]string, len(args))
+	copy(out, args)
+	for i := 0; i < len(out); i++ {
+		name := strings.TrimLeft(out[i], "-")
+		if name == out[i] || name == "" {
+			continue
+		}
+		if k, v, ok := strings.Cut(name, "="); ok {
+			if dsnFlags[k] {
+				out[i] = out[i][:len(out[i])-len(v)] + redactDSN(v)
+			}
+			continue
+		}
+		if dsnFlags[name] && i+1 < len(out) {
+			i++
+			out[i] = redactDSN(out[i])
+		}
+	}
+	return out
+}
+
+var dsnPassword = regexp.MustCompile(`(?i)(\bpassword\s*=\s*)('(?:[^'\\]|\\.)*'|\S+)`)
+
+// redactDSN masks the password of a URL ("postgres://u:p@host/db") or
+// keyword ("host=... password=p") DSN.
+func redactDSN(dsn string) string {
+	if u, err := url.Parse(dsn); err == nil && u.Scheme != "" {
+		dsn = u.Redacted()
+	}
+	return dsnPassword.ReplaceAllString(dsn, "${1}xxxxx")
+}
+
+// finish records the outcome of the run; err is what the run returned, ctx
+// the run's context. The update itself runs on a fresh context, as ctx may be
+// why the run stopped.
+func (r *migrationRun) finish(ctx context.Context, newDB *sql.DB, err error) error {
+	if r.id == 0 {
+		return nil
+	}
+
+	outcome, errText := "succeeded", sql.NullString{}
+	switch {
+	case err != nil && ctx.Err() != nil:
+		outcome = "cancelled"
+	case err != nil:
+		outcome = "failed"
+	}
+	if err != nil {
+		errText = sql.NullString{String: err.Error(), Valid: true}
+	}
+
+	r.mu.Lock()
+	var processed, skipped int64
+	for _, p := range r.phases {
+		for _, s := range p.Steps {
+			processed += s.Processed
+			skipped += s.Skipped
+		}
+	}
+	rejected := r.rejected
+	steps, jerr := json.Marshal(r.phases)
+	if r.phases == nil {
+		steps = []byte("[]")
+	}
+	r.mu.Unlock()
+	if jerr != nil {
+		return fmt.Errorf("encode migration_run id=%d steps: %w", r.id, jerr)
+	}
+
+	if _, err := newDB.ExecContext(context.Background(), `
+		UPDATE migration_run
+		SET outcome        = $2,
+		    error          = $3,
+		    rows_processed = $4,
+		    rows_skipped   = $5,
+		    rows_rejected  = $6,
+		    steps          = $7,
+		    finished_at    = now()
+		WHERE id = $1
+	`, r.id, outcome, errText, processed, skipped, rejected, steps); err != nil {
+		return fmt.Errorf("update migration_run id=%d: %w", r.id, err)
+	}
+	log.Printf("migration_run id=%d: %s, %d rows processed, %d skipped, %d rejected",
+		r.id, outcome, processed, skipped, rejected)
+	return nil
+}
+
+// startPhase marks name as the running phase.
+func (r *migrationRun) startPhase(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.phase = name
+	r.phases = append(r.phases, &phaseRun{Phase: name, Outcome: "running", start: time.Now()})
+}
+
+// endPhase closes the running phase; err is what it returned.
+func (r *migrationRun) endPhase(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.phases) == 0 {
+		return
+	}
+	p := r.phases[len(r.phases)-1]
+	p.Outcome = "succeeded"
+	if err != nil {
+		p.Outcome = "failed"
+	}
+	p.Elapsed = time.Since(p.start).Seconds()
+	r.phase = ""
+}
+
+func (r *migrationRun) currentPhase() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.phase
+}
+
+// addStep records the counts of a finished step of the running phase.
+func (r *migrationRun) addStep(step string, processed, skipped int64, elapsed time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.phases) == 0 {
+		return
+	}
+	p := r.phases[len(r.phases)-1]
+	p.Steps = append(p.Steps, &stepRun{Step: step, stepCounts: stepCounts{
+		Processed: processed,
+		Skipped:   skipped,
+		Rate:      rowsPerSecond(processed, elapsed),
+		Elapsed:   elapsed.Seconds(),
+	}})
+}
+
+// addRejects counts rows a batchWriter sent to migration_reject.
+func (r *migrationRun) addRejects(n int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rejected += n
+	if len(r.phases) > 0 {
+		r.phases[len(r.phases)-1].Rejected += n
+	}
+}
@@ -0,0 +1,980 @@
+// cmd/migrate-old-db/sync.go
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// The sync phase brings movies3db up to date with mediadb while the old apps
+// still write to it. It only touches what changed since the last sync:
+//
+//   - titles whose DateUpdated/DateAdded is at or after the sync_state
+//     watermark are upserted (parent_title_id included);
+//   - persons whose CastTable row hash differs from sync_row_hash are upserted;
+//   - for every syncJunctions table (the title_* junctions, parental guide,
+//     companies, media files and similarities), the titles whose source rows
+//     hash differently (added, changed or removed on the old side), plus the
+//     changed titles, get their new rows deleted and reloaded from the old DB;
+//   - company is re-merged in full first, as title_company needs its ID map;
+//   - when titles changed, title.next_episode_id is rebuilt as in the
+//     episode-links phase (category_id comes with the title upsert).
+//
+// The first sync has no watermark or hashes and therefore reloads everything.
+// Titles and persons deleted on the old side are not removed, and the queues
+// are left to their phase. media_file rows are reloaded without -check-files:
+// rows that are still there keep their is_missing / last_checked_at.
+
+// syncChunk is how many titles one delete-and-reload transaction covers.
+const syncChunk = 5000
+
+// syncMaps are the ID maps and new ID sets the junction jobs need.
+type syncMaps struct {
+	country, language, genre, certificate map[int32]int16
+	castRole, nominationType, connType    map[int16]int16
+	quality, display                      map[int32]int16
+	awardEvent                            map[int32]int32
+	company                               map[int64]int64 // see migrateCompanies
+	pgCategory                            map[string]int16
+	pgLevel                               map[int64]parentGuideLevel
+	titleIDs                              map[int64]struct{}
+	merges                                map[int64]int64 // merged-away person -> survivor
+}
+
+// syncJunction is one title_* table the sync phase rebuilds per changed title.
+type syncJunction struct {
+	source string // old Lines table; also its sync_row_hash source
+	target string // new table; a changed title's rows are deleted first
+	// hashSQL hashes the source per TitleID; titleLineHashSQL(source) if empty.
+	hashSQL string
+	// deleteWhere picks the target rows of the titles in $1; "title_id =
+	// ANY($1)" if empty.
+	deleteWhere string
+	// prune reloads first and then deletes only the rows the reload did not
+	// touch (updated_at before this transaction), so columns the jobs leave
+	// alone survive on the rows that are still there.
+	prune bool
+	// jobs reload the target; every srcQuery takes a TitleID range ($1, $2)
+	// like the junction loadJobs and returns "TitleID" as a column.
+	jobs func(m syncMaps) []loadJob
+}
+
+var syncJunctions = []syncJunction{
+	{source: "CountryTitleLine", target: "title_country", jobs: func(m syncMaps) []loadJob {
+		return []loadJob{titleCountryJob(m.country)}
+	}},
+	{source: "LanguageTitleLine", target: "title_language", jobs: func(m syncMaps) []loadJob {
+		return []loadJob{titleLanguageJob(m.language), originalLanguageSyncJob(m.language)}
+	}},
+	{source: "GenreTitleLine", target: "title_genre", jobs: func(m syncMaps) []loadJob {
+		return []loadJob{titleGenreJob(m.genre)}
+	}},
+	{source: "CertificateTitleLine", target: "title_certificate", jobs: func(m syncMaps) []loadJob {
+		return []loadJob{titleCertificateJob(m.country, m.certificate), primaryCertificateSyncJob(m.country, m.certificate)}
+	}},
+	{source: "CastTitleLine", target: "title_cast", jobs: func(m syncMaps) []loadJob {
+		return []loadJob{titleCastJob(m.castRole, m.merges)}
+	}},
+	{source: "KnownAsTitleLine", target: "title_alias", jobs: func(m syncMaps) []loadJob {
+		return []loadJob{aliasSyncJob()}
+	}},
+	{source: "AwardTitleLine", target: "title_award", jobs: func(m syncMaps) []loadJob {
+		return []loadJob{awardSyncJob(m.awardEvent, m.nominationType, m.merges)}
+	}},
+	{source: "ConnectionTitleLine", target: "title_connection", jobs: func(m syncMaps) []loadJob {
+		return []loadJob{connectionSyncJob(m.connType, m.titleIDs)}
+	}},
+	{source: "SimilaritiesTitleLine", target: "title_similarity",
+		deleteWhere: "title_id = ANY($1) OR similar_title_id = ANY($1)",
+		jobs: func(m syncMaps) []loadJob {
+			return []loadJob{similaritySyncJob(m.titleIDs)}
+		}},
+	{source: "CompanyTitleLine", target: "title_company", jobs: func(m syncMaps) []loadJob {
+		return []loadJob{companySyncJob(m.company)}
+	}},
+	{source: "FileTitleLine", target: "media_file", prune: true, jobs: func(m syncMaps) []loadJob {
+		return []loadJob{mediaFileSyncJob(m.quality, m.display, m.language)}
+	}},
+	{source: "TitleTable.ParentalGuide", target: "title_parental_guide",
+		hashSQL: `
+			SELECT "TitleID", md5(ROW("Nudity", "Violence", "Profanity", "AlcoholDrugSmoking", "Frightening")::text)
+			FROM "Tables"."TitleTable"
+			WHERE COALESCE("Nudity", "Violence", "Profanity", "AlcoholDrugSmoking", "Frightening") IS NOT NULL
+		`,
+		jobs: func(m syncMaps) []loadJob {
+			return []loadJob{parentalGuideSyncJob(m.pgCategory, m.pgLevel)}
+		}},
+}
+
+// MigrateSyncPhase re-migrates what changed on the old side since the last
+// sync; see the comment at the top of sync.go.
+func MigrateSyncPhase(ctx context.Context, oldDB, newDB *sql.DB, dryRun bool) error {
+	log.Printf("=== Starting migration phase=\"sync\" dryRun=%v ===", dryRun)
+
+	watermark, err := loadSyncWatermark(ctx, newDB)
+	if err != nil {
+		return err
+	}
+	titles, newWatermark, err := titlesChangedSince(ctx, oldDB, watermark)
+	if err != nil {
+		return err
+	}
+	if watermark.Valid {
+		log.Printf("sync: %d titles added/updated since %s", len(titles), watermark.Time.Format(time.RFC3339))
+	} else {
+		log.Printf("sync: no watermark yet; all %d titles count as changed", len(titles))
+	}
+
+	persons, err := diffRowHashes(ctx, oldDB, newDB, "CastTable",
+		`SELECT "CastID", md5(t::text) FROM "Tables"."CastTable" t`)
+	if err != nil {
+		return err
+	}
+	log.Printf("sync: %d persons added/changed", len(persons.changed))
+
+	junctionDiffs := make([]rowHashDiff, len(syncJunctions))
+	for i, j := range syncJunctions {
+		hashSQL := j.hashSQL
+		if hashSQL == "" {
+			hashSQL = titleLineHashSQL(j.source)
+		}
+		if junctionDiffs[i], err = diffRowHashes(ctx, oldDB, newDB, j.source, hashSQL); err != nil {
+			return err
+		}
+		log.Printf("sync: %s: %d titles with added/changed/removed rows", j.source, len(junctionDiffs[i].changed))
+	}
+
+	if dryRun {
+		for i, j := range syncJunctions {
+			log.Printf("sync [DRY-RUN]: %s: would reload rows of %d titles", j.target,
+				len(unionIDs(titles, junctionDiffs[i].changed)))
+		}
+		log.Printf("sync [DRY-RUN]: would upsert %d titles and %d persons, re-merge company and rebuild next_episode_id: %v",
+			len(titles), len(persons.changed), len(titles) > 0)
+		log.Printf("=== Migration phase=\"sync\" completed successfully ===")
+		return nil
+	}
+
+	if err := syncTitles(ctx, oldDB, newDB, titles); err != nil {
+		return err
+	}
+	if err := syncPersons(ctx, oldDB, newDB, persons.changed); err != nil {
+		return err
+	}
+	if err := saveRowHashes(ctx, newDB, persons); err != nil {
+		return err
+	}
+
+	m, err := loadSyncMaps(ctx, oldDB, newDB)
+	if err != nil {
+		return err
+	}
+	if m.company, err = migrateCompanies(ctx, oldDB, newDB, false); err != nil {
+		return fmt.Errorf("migrateCompanies: %w", err)
+	}
+	personIDs, err := loadNewPersonIDSet(ctx, newDB)
+	if err != nil {
+		return err
+	}
+	for i, j := range syncJunctions {
+		ids := unionIDs(titles, junctionDiffs[i].changed)
+		if err := reloadJunction(ctx, oldDB, newDB, j, j.jobs(m), ids, m.titleIDs, personIDs); err != nil {
+			return err
+		}
+		if err := saveRowHashes(ctx, newDB, junctionDiffs[i]); err != nil {
+			return err
+		}
+	}
+	if err := syncEpisodeLinks(ctx, oldDB, newDB, titles, m.titleIDs); err != nil {
+		return err
+	}
+
+	if err := saveSyncWatermark(ctx, newDB, newWatermark); err != nil {
+		return err
+	}
+
+	log.Printf("=== Migration phase=\"sync\" completed successfully ===")
+	return nil
+}
+
+// ======================
+//   WATERMARK
+// ======================
+
+func loadSyncWatermark(ctx context.Context, newDB *sql.DB) (sql.NullTime, error) {
+	var wm sql.NullTime
+	err := newDB.QueryRowContext(ctx, `SELECT watermark FROM sync_state WHERE source = 'title'`).Scan(&wm)
+	if err != nil && err != sql.ErrNoRows {
+		return wm, fmt.Errorf("load sync watermark: %w", err)
+	}
+	return wm, nil
+}
+
+func saveSyncWatermark(ctx context.Context, newDB *sql.DB, wm sql.NullTime) error {
+	if !wm.Valid {
+		return nil
+	}
+	if _, err := newDB.ExecContext(ctx, `
+		INSERT INTO sync_state (source, watermark, updated_at)
+		VALUES ('title', $1, now())
+		ON CONFLICT (source) DO UPDATE
+		SET watermark = EXCLUDED.watermark, updated_at = now()
+	`, wm.Time); err != nil {
+		return fmt.Errorf("save sync watermark: %w", err)
+	}
+	log.Printf("sync: watermark is now %s", wm.Time.Format(time.RFC3339))
+	return nil
+}
+
+// titlesChangedSince returns the TitleIDs added or updated at or after wm (all
+// titles when wm is NULL) and the new watermark. The watermark is read first,
+// so a title written during the sync is picked up again next time.
+func titlesChangedSince(ctx context.Context, oldDB *sql.DB, wm sql.NullTime) ([]int64, sql.NullTime, error) {
+	var next sql.NullTime
+	if err := oldDB.QueryRowContext(ctx,
+		`SELECT MAX(GREATEST("DateUpdated", "DateAdded")) FROM "Tables"."TitleTable"`).Scan(&next); err != nil {
+		return nil, next, fmt.Errorf("read TitleTable watermark: %w", err)
+	}
+
+	var since interface{}
+	if wm.Valid {
+		since = wm.Time
+	}
+	rows, err := oldDB.QueryContext(ctx, `
+		SELECT "TitleID"
+		FROM "Tables"."TitleTable"
+		WHERE $1::timestamp IS NULL
+		   OR GREATEST("DateUpdated", "DateAdded") >= $1::timestamp
+		ORDER BY "TitleID"
+	`, since)
+	if err != nil {
+		return nil, next, fmt.Errorf("select changed titles: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, next, fmt.Errorf("scan changed title: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, next, fmt.Errorf("iterate changed titles: %w", err)
+	}
+	return ids, next, nil
+}
+
+// ======================
+//   ROW HASHES
+// ======================
+
+// rowHashDiff is the result of comparing one old table's per-key hashes with
+// sync_row_hash.
+type rowHashDiff struct {
+	source  string
+	current map[int64]string // key -> hash on the old side now
+	changed []int64          // keys added, changed or removed, sorted
+	removed []int64          // keys gone from the old side
+}
+
+// titleLineHashSQL hashes all rows of a Lines table per TitleID.
+func titleLineHashSQL(table string) string {
+	return fmt.Sprintf(`
+		SELECT "TitleID", md5(string_agg(t::text, '|' ORDER BY t::text))
+		FROM "Lines".%s t
+		GROUP BY "TitleID"
+	`, pq.QuoteIdentifier(table))
+}
+
+// diffRowHashes runs hashSQL (key, md5) on the old DB and compares it with the
+// hashes stored for source by the last sync.
+func diffRowHashes(ctx context.Context, oldDB, newDB *sql.DB, source, hashSQL string) (rowHashDiff, error) {
+	d := rowHashDiff{source: source, current: make(map[int64]string)}
+
+	stored := make(map[int64]string)
+	rows, err := newDB.QueryContext(ctx, `SELECT key_id, row_hash FROM sync_row_hash WHERE source = $1`, source)
+	if err != nil {
+		return d, fmt.Errorf("select sync_row_hash %s: %w", source, err)
+	}
+	for rows.Next() {
+		var key int64
+		var hash string
+		if err := rows.Scan(&key, &hash); err != nil {
+			rows.Close()
+			return d, fmt.Errorf("scan sync_row_hash %s: %w", source, err)
+		}
+		stored[key] = hash
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return d, fmt.Errorf("iterate sync_row_hash %s: %w", source, err)
+	}
+
+	rows, err = oldDB.QueryContext(ctx, hashSQL)
+	if err != nil {
+		return d, fmt.Errorf("hash %s: %w", source, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var key int64
+		var hash string
+		if err := rows.Scan(&key, &hash); err != nil {
+			return d, fmt.Errorf("scan %s hash: %w", source, err)
+		}
+		d.current[key] = hash
+		if stored[key] != hash {
+			d.changed = append(d.changed, key)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return d, fmt.Errorf("iterate %s hashes: %w", source, err)
+	}
+
+	for key := range stored {
+		if _, ok := d.current[key]; !ok {
+			d.removed = append(d.removed, key)
+			d.changed = append(d.changed, key)
+		}
+	}
+	sort.Slice(d.changed, func(i, j int) bool { return d.changed[i] < d.changed[j] })
+	return d, nil
+}
+
+// saveRowHashes stores the hashes of the changed keys and forgets removed ones.
+// Call it only once the rows behind them have been re-migrated.
+func saveRowHashes(ctx context.Context, newDB *sql.DB, d rowHashDiff) error {
+	if len(d.changed) == 0 {
+		return nil
+	}
+	tx, err := newDB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx (sync_row_hash %s): %w", d.source, err)
+	}
+	defer tx.Rollback()
+
+	if len(d.removed) > 0 {
+		if _, err := tx.ExecContext(ctx,
+			`DELETE FROM sync_row_hash WHERE source = $1 AND key_id = ANY($2)`,
+			d.source, pq.Array(d.removed)); err != nil {
+			return fmt.Errorf("delete sync_row_hash %s: %w", d.source, err)
+		}
+	}
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO sync_row_hash (source, key_id, row_hash)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (source, key_id) DO UPDATE
+		SET row_hash = EXCLUDED.row_hash
+	`)
+	if err != nil {
+		return fmt.Errorf("prepare upsert sync_row_hash: %w", err)
+	}
+	defer stmt.Close()
+	for _, key := range d.changed {
+		hash, ok := d.current[key]
+		if !ok {
+			continue // removed
+		}
+		if _, err := stmt.ExecContext(ctx, d.source, key, hash); err != nil {
+			return fmt.Errorf("upsert sync_row_hash %s key=%d: %w", d.source, key, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit sync_row_hash %s: %w", d.source, err)
+	}
+	return nil
+}
+
+// ======================
+//   TITLES AND PERSONS
+// ======================
+
+// syncTitles upserts the given titles and sets their parent_title_id, one
+// transaction per syncChunk titles.
+func syncTitles(ctx context.Context, oldDB, newDB *sql.DB, ids []int64) error {
+	refs, err := loadTitleRefMaps(ctx, newDB)
+	if err != nil {
+		return err
+	}
+	job := loadJob{
+		target:     "title",
+		columns:    titleInsertColumns,
+		keyColumns: []string{"id"},
+		noUpdate:   []string{"parent_title_id"},
+		srcQuery:   `SELECT * FROM (` + titleSelectSQL + `) s WHERE s."TitleID" = ANY($2)`,
+		scan: func(rows *sql.Rows) ([]interface{}, bool, error) {
+			_, values, err := refs.scanTitleRow(rows)
+			return values, err == nil, err
+		},
+	}
+	progress := newJobProgress("title", int64(len(ids)))
+
+	err = forEachChunk(ctx, newDB, ids, func(tx *sql.Tx, chunk []int64) error {
+		cj := job
+		cj.srcArgs = []interface{}{0, pq.Array(chunk)}
+		if err := insertJobRows(ctx, oldDB, tx, cj, nil, nil, progress); err != nil {
+			return err
+		}
+		return syncTitleParents(ctx, oldDB, tx, chunk)
+	})
+	if err != nil {
+		return fmt.Errorf("sync titles: %w", err)
+	}
+	progress.logDone()
+	return nil
+}
+
+// syncTitleParents sets parent_title_id for the given titles from
+// TitleTable.ParentID, as backfillTitleParents does for the full load. Parents
+// missing from the new DB are left NULL.
+func syncTitleParents(ctx context.Context, oldDB *sql.DB, tx *sql.Tx, ids []int64) error {
+	rows, err := oldDB.QueryContext(ctx, `
+		SELECT "TitleID", "ParentID"
+		FROM "Tables"."TitleTable"
+		WHERE "TitleID" = ANY($1)
+		  AND "ParentID" > 0
+	`, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("query ParentID rows: %w", err)
+	}
+	var titles, parents []int64
+	for rows.Next() {
+		var titleID, parentID int64
+		if err := rows.Scan(&titleID, &parentID); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan ParentID row: %w", err)
+		}
+		titles = append(titles, titleID)
+		parents = append(parents, parentID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate ParentID rows: %w", err)
+	}
+	if len(titles) == 0 {
+		return nil
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE title t
+		SET parent_title_id = p.parent_id
+		FROM unnest($1::bigint[], $2::bigint[]) AS p(id, parent_id)
+		WHERE t.id = p.id
+		  AND t.parent_title_id IS DISTINCT FROM p.parent_id
+		  AND EXISTS (SELECT 1 FROM title WHERE id = p.parent_id)
+	`, pq.Array(titles), pq.Array(parents)); err != nil {
+		return fmt.Errorf("update title.parent_title_id: %w", err)
+	}
+	return nil
+}
+
+// syncEpisodeLinks rebuilds title.next_episode_id when titles changed. A
+// changed title can move within its parent's order or change its
+// PreviousTitleID/NextTitleID, which also changes its neighbours' links, so
+// the links are recomputed in full; writeNextEpisodeLinks clears stale ones.
+func syncEpisodeLinks(ctx context.Context, oldDB, newDB *sql.DB, titles []int64, titleIDs map[int64]struct{}) error {
+	if len(titles) == 0 {
+		log.Printf("sync: title.next_episode_id: nothing changed")
+		return nil
+	}
+	episodes, err := loadNewEpisodes(ctx, newDB)
+	if err != nil {
+		return fmt.Errorf("loadNewEpisodes: %w", err)
+	}
+	links, err := loadOldEpisodeLinks(ctx, oldDB)
+	if err != nil {
+		return fmt.Errorf("loadOldEpisodeLinks: %w", err)
+	}
+	next := reportEpisodeLinks(episodes, links, deriveNextEpisodes(episodes), titleIDs)
+	if err := writeNextEpisodeLinks(ctx, newDB, next, false); err != nil {
+		return fmt.Errorf("writeNextEpisodeLinks: %w", err)
+	}
+	return nil
+}
+
+// syncPersons upserts the given persons. Persons merge-persons merged away are
+// left out: their changes would re-create the person the merge deleted.
+func syncPersons(ctx context.Context, oldDB, newDB *sql.DB, ids []int64) error {
+	merges, err := loadPersonMerges(ctx, newDB)
+	if err != nil {
+		return err
+	}
+	kept := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		if _, ok := merges[id]; !ok {
+			kept = append(kept, id)
+		}
+	}
+	if n := len(ids) - len(kept); n > 0 {
+		log.Printf("sync: %d changed persons were merged into others; not re-created", n)
+	}
+	ids = kept
+
+	job := loadJob{
+		target:     "person",
+		columns:    personInsertColumns,
+		keyColumns: []string{"id"},
+		extraSet:   "updated_at = now()",
+		srcQuery:   `SELECT * FROM (` + personSelectSQL + `) s WHERE s."CastID" = ANY($2)`,
+		scan: func(rows *sql.Rows) ([]interface{}, bool, error) {
+			_, values, err := scanPersonRow(rows)
+			return values, err == nil, err
+		},
+	}
+	progress := newJobProgress("person", int64(len(ids)))
+
+	err = forEachChunk(ctx, newDB, ids, func(tx *sql.Tx, chunk []int64) error {
+		cj := job
+		cj.srcArgs = []interface{}{0, pq.Array(chunk)}
+		return insertJobRows(ctx, oldDB, tx, cj, nil, nil, progress)
+	})
+	if err != nil {
+		return fmt.Errorf("sync persons: %w", err)
+	}
+	progress.logDone()
+	return nil
+}
+
+// ======================
+//   JUNCTIONS
+// ======================
+
+// reloadJunction deletes the target rows of the given titles and reloads them
+// from the old DB, so rows deleted on the old side disappear too. With
+// j.prune the reload runs first and only the rows it did not touch are
+// deleted.
+func reloadJunction(
+	ctx context.Context,
+	oldDB, newDB *sql.DB,
+	j syncJunction,
+	jobs []loadJob,
+	ids []int64,
+	titleIDs, personIDs map[int64]struct{},
+) error {
+	if len(ids) == 0 {
+		log.Printf("sync: %s: nothing changed", j.target)
+		return nil
+	}
+	progress := newJobProgress(j.target, 0)
+
+	where := j.deleteWhere
+	if where == "" {
+		where = "title_id = ANY($1)"
+	}
+	deleteSQL := fmt.Sprintf(`DELETE FROM %s WHERE (%s)`, j.target, where)
+	if j.prune {
+		deleteSQL += ` AND updated_at < now()`
+	}
+
+	var deleted int64
+	err := forEachChunk(ctx, newDB, ids, func(tx *sql.Tx, chunk []int64) error {
+		del := func() error {
+			res, err := tx.ExecContext(ctx, deleteSQL, pq.Array(chunk))
+			if err != nil {
+				return fmt.Errorf("delete %s rows: %w", j.target, err)
+			}
+			n, _ := res.RowsAffected()
+			deleted += n
+			return nil
+		}
+
+		if !j.prune {
+			if err := del(); err != nil {
+				return err
+			}
+		}
+		for _, job := range jobs {
+			job.srcQuery = `SELECT * FROM (` + job.srcQuery + `) s WHERE s."TitleID" = ANY($3)`
+			job.srcArgs = []interface{}{int64(0), int64(math.MaxInt64), pq.Array(chunk)}
+			if err := insertJobRows(ctx, oldDB, tx, job, titleIDs, personIDs, progress); err != nil {
+				return err
+			}
+		}
+		if j.prune {
+			return del()
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("sync %s: %w", j.target, err)
+	}
+	logStepDone(j.target, progress.processed.Load(), progress.skipped.Load(), progress.start,
+		"sync: %s: %d titles reloaded, %d rows deleted, %d rows written, %d skipped",
+		j.target, len(ids), deleted, progress.processed.Load(), progress.skipped.Load())
+	return nil
+}
+
+// forEachChunk runs fn for each syncChunk-sized slice of ids in its own
+// transaction.
+func forEachChunk(ctx context.Context, newDB *sql.DB, ids []int64, fn func(tx *sql.Tx, chunk []int64) error) error {
+	for start := 0; start < len(ids); start += syncChunk {
+		end := start + syncChunk
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		tx, err := newDB.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("begin tx: %w", err)
+		}
+		if err := fn(tx, ids[start:end]); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("commit: %w", err)
+		}
+	}
+	return nil
+}
+
+func loadSyncMaps(ctx context.Context, oldDB, newDB *sql.DB) (syncMaps, error) {
+	var m syncMaps
+	var err error
+	for _, l := range []struct {
+		entity string
+		dst    *map[int32]int16
+	}{
+		{"country", &m.country},
+		{"language", &m.language},
+		{"genre", &m.genre},
+		{"certificate", &m.certificate},
+		{"quality", &m.quality},
+		{"display", &m.display},
+	} {
+		if *l.dst, err = loadIDMap32to16(ctx, newDB, l.entity); err != nil {
+			return m, fmt.Errorf("load %s ID map: %w", l.entity, err)
+		}
+	}
+	for _, l := range []struct {
+		entity string
+		dst    *map[int16]int16
+	}{
+		{"cast_role_type", &m.castRole},
+		{"award_nomination_type", &m.nominationType},
+		{"connection_type", &m.connType},
+	} {
+		if *l.dst, err = loadIDMap16to16(ctx, newDB, l.entity); err != nil {
+			return m, fmt.Errorf("load %s ID map: %w", l.entity, err)
+		}
+	}
+	if m.awardEvent, err = loadIDMap32to32(ctx, newDB, "award_event"); err != nil {
+		return m, fmt.Errorf("load award_event ID map: %w", err)
+	}
+	if m.pgCategory, err = buildParentalGuideCategoryMap(ctx, newDB); err != nil {
+		return m, err
+	}
+	if m.pgLevel, err = loadParentGuideLevels(ctx, oldDB); err != nil {
+		return m, err
+	}
+	if m.titleIDs, err = loadNewTitleIDSet(ctx, newDB); err != nil {
+		return m, err
+	}
+	if m.merges, err = loadPersonMerges(ctx, newDB); err != nil {
+		return m, err
+	}
+	return m, nil
+}
+
+// unionIDs merges two sorted ID lists.
+func unionIDs(a, b []int64) []int64 {
+	out := make([]int64, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case j == len(b) || (i < len(a) && a[i] < b[j]):
+			out = append(out, a[i])
+			i++
+		case i == len(a) || b[j] < a[i]:
+			out = append(out, b[j])
+			j++
+		default:
+			out = append(out, a[i])
+			i++
+			j++
+		}
+	}
+	return out
+}
+
+// ======================
+//   SYNC-ONLY JOBS
+// ======================
+
+// The full phases load these with their own loops; sync reloads them per title
+// as loadJobs. Same TitleID range contract as phase_junctions_jobs.go.
+
+// TitleTable.TitleLanguage -> title_language.is_original
+func originalLanguageSyncJob(langIDMap map[int32]int16) loadJob {
+	return loadJob{
+		target:       "title_language",
+		columns:      []string{"title_id", "language_id", "is_original"},
+		keyColumns:   []string{"title_id", "language_id"},
+		requireTitle: true,
+		srcQuery: `
+			SELECT "TitleID", "TitleLanguage"
+			FROM "Tables"."TitleTable"
+			WHERE "TitleLanguage" IS NOT NULL
+			  AND "TitleID" >= $1 AND "TitleID" < $2
+		`,
+		scan: func(rows *sql.Rows) ([]interface{}, bool, error) {
+			var titleID int64
+			var oldLangID int32
+			if err := rows.Scan(&titleID, &oldLangID); err != nil {
+				return nil, false, fmt.Errorf("scan TitleTable.TitleLanguage: %w", err)
+			}
+			newLangID, ok := langIDMap[oldLangID]
+			return []interface{}{titleID, newLangID, true}, ok, nil
+		},
+	}
+}
+
+// TitleTable.TitleCertificate -> title_certificate for the primary country
+func primaryCertificateSyncJob(countryIDMap, certIDMap map[int32]int16) loadJob {
+	job := titleCertificateJob(countryIDMap, certIDMap)
+	job.srcQuery = `
+		SELECT "TitleID", "TitleCertificate", "TitleCountry"
+		FROM "Tables"."TitleTable"
+		WHERE "TitleCertificate" IS NOT NULL
+		  AND "TitleCountry" IS NOT NULL
+		  AND "TitleID" >= $1 AND "TitleID" < $2
+	`
+	return job
+}
+
+// KnownAsTitleLine -> title_alias
+func aliasSyncJob() loadJob {
+	return loadJob{
+		target:       "title_alias",
+		columns:      []string{"title_id", "alias"},
+		keyColumns:   []string{"title_id", "alias"},
+		doNothing:    true,
+		requireTitle: true,
+		srcQuery: `
+			SELECT "TitleID", "KnownAs"
+			FROM "Lines"."KnownAsTitleLine"
+			WHERE "TitleID" >= $1 AND "TitleID" < $2
+		`,
+		scan: func(rows *sql.Rows) ([]interface{}, bool, error) {
+			var titleID int64
+			var alias string
+			if err := rows.Scan(&titleID, &alias); err != nil {
+				return nil, false, fmt.Errorf("scan KnownAsTitleLine: %w", err)
+			}
+			return []interface{}{titleID, alias}, true, nil
+		},
+	}
+}
+
+// AwardTitleLine -> title_award (award_year parsed as in migrateTitleAward)
+func awardSyncJob(eventIDMap map[int32]int32, nomIDMap map[int16]int16, merges map[int64]int64) loadJob {
+	return loadJob{
+		target:        "title_award",
+		columns:       []string{"title_id", "person_id", "event_id", "nomination_type_id", "award_year", "description", "category"},
+		keyColumns:    []string{"title_id", "person_id", "event_id", "nomination_type_id", "award_year", "category", "description"},
+		doNothing:     true,
+		requireTitle:  true,
+		requirePerson: true,
+		srcQuery: `
+			SELECT "TitleID", "CastID", "EventID", "NominationType", "AwardYear", "Description", "Category"
+			FROM "Lines"."AwardTitleLine"
+			WHERE "TitleID" >= $1 AND "TitleID" < $2
+		`,
+		scan: func(rows *sql.Rows) ([]interface{}, bool, error) {
+			var (
+				titleID, castID int64
+				oldEventID      int32
+				oldNomID        int16
+				awardYear       string
+				description     sql.NullString
+				category        sql.NullString
+			)
+			if err := rows.Scan(&titleID, &castID, &oldEventID, &oldNomID, &awardYear, &description, &category); err != nil {
+				return nil, false, fmt.Errorf("scan AwardTitleLine: %w", err)
+			}
+			newEventID, okEvent := eventIDMap[oldEventID]
+			newNomID, okNom := nomIDMap[oldNomID]
+			if !okEvent || !okNom {
+				return nil, false, nil
+			}
+			var year interface{}
+			if y, ok := parseAwardYear(awardYear); ok {
+				year = y
+			}
+			return []interface{}{titleID, resolvePerson(merges, castID), newEventID, newNomID, year,
+				nullStringOrNil(description), nullStringOrNil(category)}, true, nil
+		},
+	}
+}
+
+// ConnectionTitleLine -> title_connection; both titles must exist.
+func connectionSyncJob(connTypeIDMap map[int16]int16, titleIDs map[int64]struct{}) loadJob {
+	return loadJob{
+		target:       "title_connection",
+		columns:      []string{"title_id", "other_title_id", "connection_type_id"},
+		keyColumns:   []string{"title_id", "other_title_id", "connection_type_id"},
+		doNothing:    true,
+		requireTitle: true,
+		srcQuery: `
+			SELECT "TitleID", "ConnectionTitleID", "ConnectionType"
+			FROM "Lines"."ConnectionTitleLine"
+			WHERE "TitleID" >= $1 AND "TitleID" < $2
+		`,
+		scan: func(rows *sql.Rows) ([]interface{}, bool, error) {
+			var titleID, otherTitleID int64
+			var oldTypeID int16
+			if err := rows.Scan(&titleID, &otherTitleID, &oldTypeID); err != nil {
+				return nil, false, fmt.Errorf("scan ConnectionTitleLine: %w", err)
+			}
+			if _, ok := titleIDs[otherTitleID]; !ok {
+				return nil, false, nil
+			}
+			newTypeID, ok := connTypeIDMap[oldTypeID]
+			return []interface{}{titleID, otherTitleID, newTypeID}, ok, nil
+		},
+	}
+}
+
+// SimilaritiesTitleLine -> title_similarity, folded to (LEAST, GREATEST) as in
+// migrateTitleSimilarity. Each pair is returned under both of its titles, so
+// a changed title also gets back the pairs stored under the other one.
+func similaritySyncJob(titleIDs map[int64]struct{}) loadJob {
+	return loadJob{
+		target:     "title_similarity",
+		columns:    []string{"title_id", "similar_title_id"},
+		keyColumns: []string{"title_id", "similar_title_id"},
+		doNothing:  true,
+		srcQuery: `
+			SELECT DISTINCT "TitleID", a, b
+			FROM (
+				SELECT "TitleID",
+				       LEAST("TitleID", "SimilarTitleID")    AS a,
+				       GREATEST("TitleID", "SimilarTitleID") AS b
+				FROM "Lines"."SimilaritiesTitleLine"
+				UNION ALL
+				SELECT "SimilarTitleID",
+				       LEAST("TitleID", "SimilarTitleID"),
+				       GREATEST("TitleID", "SimilarTitleID")
+				FROM "Lines"."SimilaritiesTitleLine"
+			) p
+			WHERE a <> b
+			  AND "TitleID" >= $1 AND "TitleID" < $2
+		`,
+		scan: func(rows *sql.Rows) ([]interface{}, bool, error) {
+			var titleID, a, b int64
+			if err := rows.Scan(&titleID, &a, &b); err != nil {
+				return nil, false, fmt.Errorf("scan SimilaritiesTitleLine: %w", err)
+			}
+			_, okA := titleIDs[a]
+			_, okB := titleIDs[b]
+			return []interface{}{a, b}, okA && okB, nil
+		},
+	}
+}
+
+// CompanyTitleLine -> title_company
+func companySyncJob(companyIDMap map[int64]int64) loadJob {
+	return loadJob{
+		target:       "title_company",
+		columns:      []string{"title_id", "company_id"},
+		keyColumns:   []string{"title_id", "company_id"},
+		doNothing:    true,
+		requireTitle: true,
+		srcQuery: `
+			SELECT "TitleID", "CompanyID"
+			FROM "Lines"."CompanyTitleLine"
+			WHERE "TitleID" >= $1 AND "TitleID" < $2
+		`,
+		scan: func(rows *sql.Rows) ([]interface{}, bool, error) {
+			var titleID, oldCompanyID int64
+			if err := rows.Scan(&titleID, &oldCompanyID); err != nil {
+				return nil, false, fmt.Errorf("scan CompanyTitleLine: %w", err)
+			}
+			newCompanyID, ok := companyIDMap[oldCompanyID]
+			return []interface{}{titleID, newCompanyID}, ok, nil
+		},
+	}
+}
+
+// FileTitleLine -> media_file, as in migrateMediaFiles but without folder
+// checks: is_missing and last_checked_at are left to the media-files phase.
+func mediaFileSyncJob(qualityIDMap, displayIDMap, langIDMap map[int32]int16) loadJob {
+	columns := []string{"title_id", "quality_id", "display_id", "file_path", "audio_language_id", "subtitle_language_id"}
+	return loadJob{
+		target:       "media_file",
+		columns:      columns,
+		keyColumns:   columns, // media_file_uq
+		extraSet:     "updated_at = now()",
+		requireTitle: true,
+		srcQuery: `
+			SELECT f."TitleID", f."QualityID", f."DisplayID", f."AudioLanguageID", f."SubtitleLanguageID",
+			       t."FolderPath", t."FolderName"
+			FROM "Lines"."FileTitleLine" f
+			JOIN "Tables"."TitleTable" t ON t."TitleID" = f."TitleID"
+			WHERE f."TitleID" >= $1 AND f."TitleID" < $2
+		`,
+		scan: func(rows *sql.Rows) ([]interface{}, bool, error) {
+			var (
+				titleID                                       int64
+				oldQuality, oldDisplay, oldAudio, oldSubtitle int32
+				folderPath                                    sql.NullString
+				folderName                                    string
+			)
+			if err := rows.Scan(&titleID, &oldQuality, &oldDisplay, &oldAudio, &oldSubtitle, &folderPath, &folderName); err != nil {
+				return nil, false, fmt.Errorf("scan FileTitleLine: %w", err)
+			}
+			return []interface{}{titleID,
+				mappedIDOrNil(qualityIDMap, oldQuality), mappedIDOrNil(displayIDMap, oldDisplay),
+				joinLegacyPath(folderPath.String, folderName),
+				mappedIDOrNil(langIDMap, oldAudio), mappedIDOrNil(langIDMap, oldSubtitle)}, true, nil
+		},
+	}
+}
+
+// TitleTable parental guide columns -> title_parental_guide, unpivoted on the
+// old side. Values that are not ParentGuideRef ids are skipped; the
+// parental-guide phase is what sends them to migration_reject.
+func parentalGuideSyncJob(categoryIDs map[string]int16, levels map[int64]parentGuideLevel) loadJob {
+	return loadJob{
+		target:       "title_parental_guide",
+		columns:      []string{"title_id", "category_id", "severity", "description"},
+		keyColumns:   []string{"title_id", "category_id"},
+		requireTitle: true,
+		srcQuery: `
+			SELECT t."TitleID", v.col, v.val
+			FROM "Tables"."TitleTable" t
+			CROSS JOIN LATERAL (VALUES
+				('Nudity', t."Nudity"),
+				('Violence', t."Violence"),
+				('Profanity', t."Profanity"),
+				('AlcoholDrugSmoking', t."AlcoholDrugSmoking"),
+				('Frightening', t."Frightening")
+			) AS v(col, val)
+			WHERE v.val IS NOT NULL
+			  AND t."TitleID" >= $1 AND t."TitleID" < $2
+		`,
+		scan: func(rows *sql.Rows) ([]interface{}, bool, error) {
+			var titleID, value int64
+			var column string
+			if err := rows.Scan(&titleID, &column, &value); err != nil {
+				return nil, false, fmt.Errorf("scan TitleTable parental guide: %w", err)
+			}
+			categoryID, okCategory := categoryIDs[column]
+			level, okLevel := levels[value]
+			if !okCategory || !okLevel {
+				return nil, false, nil
+			}
+			return []interface{}{titleID, categoryID, level.severity, level.description}, true, nil
+		},
+	}
+}
@@ -26,6 +26,12 @@ CREATE TABLE genre_ref (
     name        TEXT NOT NULL UNIQUE
 );
 
+-- Library-level category (old References."CategoryRef")
+CREATE TABLE category_ref (
+    id          SMALLINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    name        TEXT NOT NULL UNIQUE
+);
+
 CREATE TABLE certificate_ref (
     id          SMALLINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
     name        TEXT NOT NULL UNIQUE,
@@ -103,10 +109,14 @@ CREATE TABLE person (
     birth_year          SMALLINT,
     death_year          SMALLINT,
     primary_profession  TEXT,
+    image_url           TEXT,
+    bio                 TEXT,
     created_at          TIMESTAMPTZ NOT NULL DEFAULT now(),
     updated_at          TIMESTAMPTZ NOT NULL DEFAULT now()
 );
 
+-- next_episode_id keeps the explicit "next episode" link from the old
+-- TitleTable.NextTitleID (see the episode-links migration phase).
 CREATE TABLE title (
     id                  INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
     imdb_id             TEXT UNIQUE,    -- tconst
@@ -126,10 +136,15 @@ CREATE TABLE title (
     parent_title_id     INTEGER,
     season_number       INTEGER,
     episode_number      INTEGER,
+    next_episode_id     INTEGER,
     total_seasons       INTEGER,
     total_episodes      INTEGER,
     date_released       DATE,
 
+    plot                TEXT,
+    storyline           TEXT,
+    category_id         SMALLINT,
+
     date_added          TIMESTAMPTZ NOT NULL DEFAULT now(),
     date_updated        TIMESTAMPTZ NOT NULL DEFAULT now(),
 
@@ -160,9 +175,25 @@ CREATE TABLE title (
     CONSTRAINT title_parent_title_fk
         FOREIGN KEY (parent_title_id)
         REFERENCES title (id)
+        ON UPDATE CASCADE ON DELETE SET NULL,
+
+    CONSTRAINT title_category_fk
+        FOREIGN KEY (category_id)
+        REFERENCES category_ref (id)
+        ON UPDATE CASCADE ON DELETE SET NULL,
+
+    CONSTRAINT title_next_episode_fk
+        FOREIGN KEY (next_episode_id)
+        REFERENCES title (id)
         ON UPDATE CASCADE ON DELETE SET NULL
 );
 
+-- Production companies / studios (A24, Studio Ghibli, ...)
+CREATE TABLE company (
+    id          INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    name        TEXT NOT NULL UNIQUE
+);
+
 -- ===========================
 --  Title attributes / junctions
 -- ===========================
@@ -305,10 +336,48 @@ CREATE TABLE title_connection (
         ON UPDATE CASCADE ON DELETE RESTRICT
 );
 
+CREATE TABLE title_company (
+    title_id    INTEGER NOT NULL,
+    company_id  INTEGER NOT NULL,
+
+    PRIMARY KEY (title_id, company_id),
+
+    CONSTRAINT title_company_title_fk
+        FOREIGN KEY (title_id)
+        REFERENCES title (id)
+        ON UPDATE CASCADE ON DELETE CASCADE,
+
+    CONSTRAINT title_company_company_fk
+        FOREIGN KEY (company_id)
+        REFERENCES company (id)
+        ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+-- Symmetric "more like this" pairs: each pair is stored once with title_id < similar_title_id.
+CREATE TABLE title_similarity (
+    title_id            INTEGER NOT NULL,
+    similar_title_id    INTEGER NOT NULL,
+
+    PRIMARY KEY (title_id, similar_title_id),
+
+    CONSTRAINT title_similarity_ordered_chk
+        CHECK (title_id < similar_title_id),
+
+    CONSTRAINT title_similarity_title_fk
+        FOREIGN KEY (title_id)
+        REFERENCES title (id)
+        ON UPDATE CASCADE ON DELETE CASCADE,
+
+    CONSTRAINT title_similarity_similar_title_fk
+        FOREIGN KEY (similar_title_id)
+        REFERENCES title (id)
+        ON UPDATE CASCADE ON DELETE CASCADE
+);
+
 CREATE TABLE title_parental_guide (
     title_id        INTEGER NOT NULL,
     category_id     SMALLINT NOT NULL,
-    severity        SMALLINT NOT NULL,   -- e.g. 0–4
+    severity        SMALLINT NOT NULL,   -- 0 None / 1 Mild / 2 Moderate / 3 Severe
     description     TEXT,
 
     PRIMARY KEY (title_id, category_id),
@@ -324,7 +393,10 @@ CREATE TABLE title_parental_guide (
         ON UPDATE CASCADE ON DELETE RESTRICT
 );
 
+-- award_year / category may be unknown (NULL), so they can't be part of a PK.
+-- NULLS NOT DISTINCT keeps such rows unique instead of letting them duplicate.
 CREATE TABLE title_award (
+    id                  BIGSERIAL PRIMARY KEY,
     title_id            INTEGER NOT NULL,
     person_id           BIGINT NOT NULL,
     event_id            INTEGER NOT NULL,
@@ -333,7 +405,8 @@ CREATE TABLE title_award (
     description         TEXT,
     category            TEXT,
 
-    PRIMARY KEY (title_id, person_id, event_id, nomination_type_id, award_year, category),
+    CONSTRAINT title_award_uq
+        UNIQUE NULLS NOT DISTINCT (title_id, person_id, event_id, nomination_type_id, award_year, category, description),
 
     CONSTRAINT title_award_title_fk
         FOREIGN KEY (title_id)
@@ -374,6 +447,9 @@ CREATE TABLE media_file (
     created_at              TIMESTAMPTZ NOT NULL DEFAULT now(),
     updated_at              TIMESTAMPTZ NOT NULL DEFAULT now(),
 
+    CONSTRAINT media_file_uq
+        UNIQUE NULLS NOT DISTINCT (title_id, file_path, quality_id, display_id, audio_language_id, subtitle_language_id),
+
     CONSTRAINT media_file_title_fk
         FOREIGN KEY (title_id)
         REFERENCES title (id)
@@ -426,21 +502,170 @@ CREATE TABLE title_tag (
         ON UPDATE CASCADE ON DELETE CASCADE
 );
 
+-- title_id is set when the requested / missing title is already in the library.
 CREATE TABLE requested_title (
     id              BIGSERIAL PRIMARY KEY,
+    title_id        INTEGER UNIQUE,
+    old_title_id    INTEGER UNIQUE,
     imdb_id         TEXT,
     title_name      TEXT,
     requested_by    TEXT,
     requested_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
-    notes           TEXT
+    notes           TEXT,
+
+    CONSTRAINT requested_title_title_fk
+        FOREIGN KEY (title_id)
+        REFERENCES title (id)
+        ON UPDATE CASCADE ON DELETE SET NULL
 );
 
 CREATE TABLE not_downloaded_title (
     id              BIGSERIAL PRIMARY KEY,
+    title_id        INTEGER UNIQUE,
+    old_title_id    INTEGER UNIQUE,
     imdb_id         TEXT,
     title_name      TEXT,
     reason          TEXT,
-    last_checked_at TIMESTAMPTZ
+    last_checked_at TIMESTAMPTZ,
+
+    CONSTRAINT not_downloaded_title_title_fk
+        FOREIGN KEY (title_id)
+        REFERENCES title (id)
+        ON UPDATE CASCADE ON DELETE SET NULL
+);
+
+-- Titles whose metadata should be re-fetched (old Tables."ToBeUpdated")
+CREATE TABLE title_refresh_queue (
+    title_id        INTEGER PRIMARY KEY,
+    queued_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+    reason          TEXT,
+    last_attempt_at TIMESTAMPTZ,
+
+    CONSTRAINT title_refresh_queue_title_fk
+        FOREIGN KEY (title_id)
+        REFERENCES title (id)
+        ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+-- ===========================
+--  Migration bookkeeping
+-- ===========================
+
+-- Last committed key per migration phase/step (cmd/migrate-old-db -resume)
+CREATE TABLE migration_checkpoint (
+    phase           TEXT NOT NULL,
+    step            TEXT NOT NULL,
+    last_key        BIGINT,
+    rows_done       BIGINT NOT NULL DEFAULT 0,
+    completed_at    TIMESTAMPTZ,
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+    PRIMARY KEY (phase, step)
+);
+
+-- Rows a migration phase could not write (data or constraint error), kept
+-- so the run can go on. source_key is the old ID of the row when it has one,
+-- source_row the values that were being written, by column
+CREATE TABLE migration_reject (
+    id              BIGSERIAL PRIMARY KEY,
+    phase           TEXT NOT NULL,
+    target          TEXT NOT NULL,
+    source_key      BIGINT,
+    error           TEXT NOT NULL,
+    source_row      JSONB NOT NULL,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- One row per cmd/migrate-old-db invocation. phases is the resolved plan,
+-- steps the per-phase / per-step timings and counts as the run logged them
+CREATE TABLE migration_run (
+    id              BIGSERIAL PRIMARY KEY,
+    args            TEXT[] NOT NULL,
+    phase           TEXT NOT NULL,
+    phases          TEXT[] NOT NULL,
+    dry_run         BOOLEAN NOT NULL,
+    outcome         TEXT NOT NULL DEFAULT 'running',
+    error           TEXT,
+    rows_processed  BIGINT NOT NULL DEFAULT 0,
+    rows_skipped    BIGINT NOT NULL DEFAULT 0,
+    rows_rejected   BIGINT NOT NULL DEFAULT 0,
+    steps           JSONB NOT NULL DEFAULT '[]',
+    started_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    finished_at     TIMESTAMPTZ,
+
+    CONSTRAINT migration_run_outcome_chk
+        CHECK (outcome IN ('running', 'succeeded', 'failed', 'cancelled'))
+);
+
+-- Old ID to new ID per entity (country, language, title, person, ...)
+-- match_method is id, code, name, override (-overrides file), merge (person merged
+-- away by merge-persons) or none (new_id NULL when unmatched)
+CREATE TABLE id_map (
+    entity          TEXT NOT NULL,
+    old_id          BIGINT NOT NULL,
+    new_id          BIGINT,
+    match_method    TEXT NOT NULL,
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+    PRIMARY KEY (entity, old_id),
+
+    CONSTRAINT id_map_method_chk
+        CHECK (match_method IN ('id', 'code', 'name', 'override', 'merge', 'none')),
+    CONSTRAINT id_map_new_id_chk
+        CHECK ((new_id IS NULL) = (match_method = 'none'))
+);
+
+-- Old-side TitleTable watermark (max DateUpdated/DateAdded) of the last sync
+CREATE TABLE sync_state (
+    source          TEXT PRIMARY KEY,
+    watermark       TIMESTAMP,
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- md5 of the old rows per key (TitleID or CastID) as of the last sync, for
+-- old tables without timestamps (CastTable and the Lines tables)
+CREATE TABLE sync_row_hash (
+    source          TEXT NOT NULL,
+    key_id          BIGINT NOT NULL,
+    row_hash        TEXT NOT NULL,
+
+    PRIMARY KEY (source, key_id)
+);
+
+-- imdb_id candidates the imdb-ids phase would not write on its own: several
+-- matches, or an id already held by (or proposed for) another row.
+-- entity is title or person, source the step (pattern, title.basics, name.basics)
+CREATE TABLE imdb_id_review (
+    entity          TEXT NOT NULL,
+    entity_id       BIGINT NOT NULL,
+    source          TEXT NOT NULL,
+    candidates      TEXT[] NOT NULL,
+    reason          TEXT NOT NULL,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+    PRIMARY KEY (entity, entity_id, source),
+    CONSTRAINT imdb_id_review_entity_chk
+        CHECK (entity IN ('title', 'person'))
+);
+
+-- One row per merge-persons merge, with what it needs to be undone: both
+-- person rows as they were, the title_cast / title_award rows it moved (keys)
+-- or dropped (full rows, the winner already had them) and the id_map rows it
+-- repointed with their previous match_method
+CREATE TABLE person_merge_log (
+    id              BIGSERIAL PRIMARY KEY,
+    winner_id       BIGINT NOT NULL,
+    loser_id        BIGINT NOT NULL,
+    reason          TEXT,
+    winner_before   JSONB NOT NULL,
+    loser           JSONB NOT NULL,
+    cast_dropped    JSONB NOT NULL,
+    cast_moved      JSONB NOT NULL,
+    award_dropped   JSONB NOT NULL,
+    award_moved     JSONB NOT NULL,
+    id_map          JSONB NOT NULL,
+    merged_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+    undone_at       TIMESTAMPTZ
 );
 
 -- ===========================
@@ -467,3 +692,12 @@ CREATE INDEX idx_media_file_title
 
 CREATE INDEX idx_person_name_lower
     ON person (LOWER(name));
+
+CREATE INDEX idx_company_name_lower
+    ON company (LOWER(name));
+
+CREATE INDEX idx_title_company_company
+    ON title_company (company_id);
+
+CREATE INDEX idx_title_similarity_similar
+    ON title_similarity (similar_title_id);